@@ -11,16 +11,56 @@ import (
 )
 
 type Config struct {
-	Days            int           `json:"days"`
-	Paths           []string      `json:"paths"`
-	APaths          []string      `json:"-"`
-	IncludeSubject  bool          `json:"includeSubject"`
-	SendTo          string        `json:"sendTo"`
-	ConfigFile      string        `json:"-"`
-	ShutdownTimeout time.Duration `json:"shutdownTimeout"`
-	Extensions      []string      `json:"extensions"`
-	Server          bool          `json:"server"`
-	Port            int           `json:"port"`
+	Days            int             `json:"days"`
+	Paths           []string        `json:"paths"`
+	APaths          []string        `json:"-"`
+	IncludeSubject  bool            `json:"includeSubject"`
+	SendTo          string          `json:"sendTo"`
+	ConfigFile      string          `json:"-"`
+	ShutdownTimeout time.Duration   `json:"shutdownTimeout"`
+	Extensions      []string        `json:"extensions"`
+	Server          bool            `json:"server"`
+	Port            int             `json:"port"`
+	Passwords       []string        `json:"passwords"`
+	PasswordsFile   string          `json:"passwordsFile"`
+	Endpoints       []string        `json:"endpoints"`
+	Pairs           []string        `json:"pairs"`
+	VerifyChain     bool            `json:"verifyChain"`
+	CABundle        string          `json:"caBundle"`
+	Lint            []string        `json:"lint"`
+	Inventory       bool            `json:"inventory"`
+	KeyOwners       []string        `json:"keyOwners"`
+	Sniff           bool            `json:"sniff"`
+	Include         []string        `json:"include"`
+	Exclude         []string        `json:"exclude"`
+	Roots           map[string]Root `json:"roots"`
+	Symlinks        string          `json:"symlinks"`
+	OneFilesystem   bool            `json:"oneFilesystem"`
+	Walkers         int             `json:"walkers"`
+	Cache           string          `json:"cache"`
+	Workers         int             `json:"workers"`
+	MaxDepth        int             `json:"maxDepth"`
+	BufferSize      int             `json:"bufferSize"`
+	MaxFileSize     int64           `json:"maxFileSize"`
+	FileTimeout     time.Duration   `json:"fileTimeout"`
+	ScanBudget      time.Duration   `json:"scanBudget"`
+	FilesPerSecond  float64         `json:"filesPerSecond"`
+	BytesPerSecond  int64           `json:"bytesPerSecond"`
+	LowPriority     bool            `json:"lowPriority"`
+	Watch           bool            `json:"watch"`
+	RescanInterval  time.Duration   `json:"rescanInterval"`
+	Daemon          bool            `json:"daemon"`
+	Schedule        string          `json:"schedule"`
+	Jitter          time.Duration   `json:"jitter"`
+}
+
+// Root overrides settings for the files under one directory, usually one of
+// Paths.
+type Root struct {
+	Days       *int     `json:"days"` // the global threshold when absent
+	Extensions []string `json:"extensions"`
+	Include    []string `json:"include"`
+	Exclude    []string `json:"exclude"`
 }
 
 var (
@@ -55,14 +95,29 @@ func New() *Config {
 		Days:            30,
 		Paths:           []string{"/etc/ssl/certs", "/etc/pki", "/var/lib/kubelet/pki"},
 		ShutdownTimeout: 30 * time.Second,
-		Extensions:      []string{".pem", ".cer", ".crt", ".key"},
+		Extensions:      []string{".pem", ".cer", ".crt", ".key", ".p12", ".pfx", ".jks", ".jceks", "cacerts", ".p7b", ".p7c", ".crl", ".csr"},
 		Port:            3000,
+		Symlinks:        "follow",
+		Walkers:         8,
+		Workers:         10,
+		MaxDepth:        20,
+		BufferSize:      100,
+		MaxFileSize:     100 * 1024 * 1024,
+		FileTimeout:     time.Minute,
+		RescanInterval:  time.Hour,
+		Schedule:        "24h",
 	}
 }
 
 func (c *Config) ParseFlags() error {
 	var paths string
 	var apaths string
+	var endpoints string
+	var pairs string
+	var lint string
+	var keyOwners string
+	var include string
+	var exclude string
 	var t string
 
 	flag.IntVar(&c.Days, "days", c.Days, "Alert threshold in days before expiration")
@@ -74,6 +129,35 @@ func (c *Config) ParseFlags() error {
 	flag.StringVar(&t, "shutdown-timeout", "30s", "Maximum time to wait for graceful shutdown")
 	flag.BoolVar(&c.Server, "server", c.Server, "Run as HTTP server to receive and display alerts")
 	flag.IntVar(&c.Port, "port", c.Port, "Port for HTTP server mode")
+	flag.StringVar(&endpoints, "endpoints", "", "Comma-separated list of host:port[?sni=name] TLS endpoints to probe")
+	flag.StringVar(&pairs, "pairs", "", "Comma-separated list of path=host:port pairs to check for stale served certificates")
+	flag.BoolVar(&c.VerifyChain, "verify-chain", c.VerifyChain, "Verify the chain of leaf certificates against the system roots")
+	flag.StringVar(&c.CABundle, "ca-bundle", c.CABundle, "PEM bundle of trusted roots for chain verification (implies --verify-chain)")
+	flag.StringVar(&lint, "lint", "", "Comma-separated lint rules to enable, \"all\" for every rule, \"-rule\" to disable one")
+	flag.BoolVar(&c.Inventory, "inventory", c.Inventory, "Print a detailed record for every certificate, including alerted ones")
+	flag.StringVar(&keyOwners, "key-owners", "", "Comma-separated users or uids allowed to own private keys")
+	flag.BoolVar(&c.Sniff, "sniff", c.Sniff, "Select files by content (PEM, DER, PKCS#12, JKS) instead of by extension")
+	flag.StringVar(&include, "include", "", "Comma-separated gitignore-style patterns of the only files to scan")
+	flag.StringVar(&exclude, "exclude", "", "Comma-separated gitignore-style patterns of files and directories to skip")
+	flag.StringVar(&c.Symlinks, "symlinks", c.Symlinks, "Symbolic links policy: follow or skip")
+	flag.BoolVar(&c.OneFilesystem, "one-filesystem", c.OneFilesystem, "Do not walk into other mounts, such as proc, sysfs, NFS or FUSE")
+	flag.IntVar(&c.Walkers, "walkers", c.Walkers, "Number of directories read in parallel (0 for the default)")
+	flag.IntVar(&c.Workers, "workers", c.Workers, "Number of files parsed in parallel (0 for the default)")
+	flag.IntVar(&c.MaxDepth, "max-depth", c.MaxDepth, "Maximum directory depth below each path (0 for the top level only)")
+	flag.IntVar(&c.BufferSize, "buffer-size", c.BufferSize, "Number of files and results queued between scan stages (0 for the default)")
+	flag.Int64Var(&c.MaxFileSize, "max-file-size", c.MaxFileSize, "Maximum size in bytes of the files to parse (0 for the default)")
+	flag.DurationVar(&c.FileTimeout, "file-timeout", c.FileTimeout, "Maximum time to read and parse one file (0 for the default)")
+	flag.DurationVar(&c.ScanBudget, "scan-budget", c.ScanBudget, "Time after which no more directories are walked, 0 for no limit")
+	flag.Float64Var(&c.FilesPerSecond, "files-per-second", c.FilesPerSecond, "Maximum number of files parsed per second, 0 for no limit")
+	flag.Int64Var(&c.BytesPerSecond, "bytes-per-second", c.BytesPerSecond, "Maximum number of bytes read from files per second, 0 for no limit")
+	flag.BoolVar(&c.LowPriority, "low-priority", c.LowPriority, "Run with the lowest CPU and I/O priority")
+	flag.BoolVar(&c.Watch, "watch", c.Watch, "Keep running and parse files again as soon as they change (Linux only)")
+	flag.DurationVar(&c.RescanInterval, "rescan-interval", c.RescanInterval, "Interval of the full scans in watch mode, 0 to only scan at start")
+	flag.BoolVar(&c.Daemon, "daemon", c.Daemon, "Keep running and scan on the schedule")
+	flag.StringVar(&c.Schedule, "schedule", c.Schedule, "Interval (6h) or cron expression (0 3 * * *) of the scans in daemon mode")
+	flag.DurationVar(&c.Jitter, "jitter", c.Jitter, "Maximum random delay of each scan in daemon mode, to spread the load of a fleet")
+	flag.StringVar(&c.Cache, "cache", c.Cache, "File caching what was parsed from unchanged files between runs")
+	flag.StringVar(&c.PasswordsFile, "passwords-file", c.PasswordsFile, "File with keystore passwords to try, one per line")
 	flag.Parse()
 
 	if paths != "" {
@@ -91,6 +175,48 @@ func (c *Config) ParseFlags() error {
 		c.Paths = append(c.Paths, c.APaths...)
 	}
 
+	if endpoints != "" {
+		c.Endpoints = strings.Split(endpoints, ",")
+		for i, endpoint := range c.Endpoints {
+			c.Endpoints[i] = strings.TrimSpace(endpoint)
+		}
+	}
+
+	if pairs != "" {
+		c.Pairs = strings.Split(pairs, ",")
+		for i, pair := range c.Pairs {
+			c.Pairs[i] = strings.TrimSpace(pair)
+		}
+	}
+
+	if lint != "" {
+		c.Lint = strings.Split(lint, ",")
+		for i, rule := range c.Lint {
+			c.Lint[i] = strings.TrimSpace(rule)
+		}
+	}
+
+	if keyOwners != "" {
+		c.KeyOwners = strings.Split(keyOwners, ",")
+		for i, owner := range c.KeyOwners {
+			c.KeyOwners[i] = strings.TrimSpace(owner)
+		}
+	}
+
+	if include != "" {
+		c.Include = strings.Split(include, ",")
+		for i, pattern := range c.Include {
+			c.Include[i] = strings.TrimSpace(pattern)
+		}
+	}
+
+	if exclude != "" {
+		c.Exclude = strings.Split(exclude, ",")
+		for i, pattern := range c.Exclude {
+			c.Exclude[i] = strings.TrimSpace(pattern)
+		}
+	}
+
 	if t != "" {
 		timeout, err := time.ParseDuration(t)
 		if err != nil {
@@ -109,14 +235,45 @@ func (c *Config) ParseFlags() error {
 }
 
 type jsonConfig struct {
-	Days            int      `json:"days"`
-	Paths           []string `json:"paths"`
-	IncludeSubject  bool     `json:"includeSubject"`
-	SendTo          string   `json:"sendTo"`
-	ShutdownTimeout string   `json:"shutdownTimeout"`
-	Extensions      []string `json:"extensions"`
-	Server          bool     `json:"server"`
-	Port            int      `json:"port"`
+	Days            int             `json:"days"`
+	Paths           []string        `json:"paths"`
+	IncludeSubject  bool            `json:"includeSubject"`
+	SendTo          string          `json:"sendTo"`
+	ShutdownTimeout string          `json:"shutdownTimeout"`
+	Extensions      []string        `json:"extensions"`
+	Server          bool            `json:"server"`
+	Port            int             `json:"port"`
+	Passwords       []string        `json:"passwords"`
+	PasswordsFile   string          `json:"passwordsFile"`
+	Endpoints       []string        `json:"endpoints"`
+	Pairs           []string        `json:"pairs"`
+	VerifyChain     bool            `json:"verifyChain"`
+	CABundle        string          `json:"caBundle"`
+	Lint            []string        `json:"lint"`
+	Inventory       bool            `json:"inventory"`
+	KeyOwners       []string        `json:"keyOwners"`
+	Sniff           bool            `json:"sniff"`
+	Include         []string        `json:"include"`
+	Exclude         []string        `json:"exclude"`
+	Roots           map[string]Root `json:"roots"`
+	Symlinks        string          `json:"symlinks"`
+	OneFilesystem   bool            `json:"oneFilesystem"`
+	Walkers         int             `json:"walkers"`
+	Cache           string          `json:"cache"`
+	Workers         int             `json:"workers"`
+	MaxDepth        *int            `json:"maxDepth"`
+	BufferSize      int             `json:"bufferSize"`
+	MaxFileSize     int64           `json:"maxFileSize"`
+	FileTimeout     string          `json:"fileTimeout"`
+	ScanBudget      string          `json:"scanBudget"`
+	FilesPerSecond  float64         `json:"filesPerSecond"`
+	BytesPerSecond  int64           `json:"bytesPerSecond"`
+	LowPriority     bool            `json:"lowPriority"`
+	Watch           bool            `json:"watch"`
+	RescanInterval  string          `json:"rescanInterval"`
+	Daemon          bool            `json:"daemon"`
+	Schedule        string          `json:"schedule"`
+	Jitter          string          `json:"jitter"`
 }
 
 func (c *Config) LoadFromFile() error {
@@ -137,6 +294,54 @@ func (c *Config) LoadFromFile() error {
 	c.Extensions = fileCfg.Extensions
 	c.Server = fileCfg.Server
 	c.Port = fileCfg.Port
+	c.Passwords = fileCfg.Passwords
+	c.Endpoints = fileCfg.Endpoints
+	c.Pairs = fileCfg.Pairs
+	c.VerifyChain = fileCfg.VerifyChain
+	c.Lint = fileCfg.Lint
+	c.Inventory = fileCfg.Inventory
+	c.KeyOwners = fileCfg.KeyOwners
+	c.Sniff = fileCfg.Sniff
+	c.Include = fileCfg.Include
+	c.Exclude = fileCfg.Exclude
+	c.Roots = fileCfg.Roots
+	c.OneFilesystem = fileCfg.OneFilesystem
+	c.FilesPerSecond = fileCfg.FilesPerSecond
+	c.BytesPerSecond = fileCfg.BytesPerSecond
+	c.LowPriority = fileCfg.LowPriority
+	c.Watch = fileCfg.Watch
+	c.Daemon = fileCfg.Daemon
+	if fileCfg.Schedule != "" {
+		c.Schedule = fileCfg.Schedule
+	}
+	if fileCfg.Cache != "" {
+		c.Cache = fileCfg.Cache
+	}
+	if fileCfg.Walkers != 0 {
+		c.Walkers = fileCfg.Walkers
+	}
+	if fileCfg.Workers != 0 {
+		c.Workers = fileCfg.Workers
+	}
+	// 0 is a depth of its own, not the default
+	if fileCfg.MaxDepth != nil {
+		c.MaxDepth = *fileCfg.MaxDepth
+	}
+	if fileCfg.BufferSize != 0 {
+		c.BufferSize = fileCfg.BufferSize
+	}
+	if fileCfg.MaxFileSize != 0 {
+		c.MaxFileSize = fileCfg.MaxFileSize
+	}
+	if fileCfg.Symlinks != "" {
+		c.Symlinks = fileCfg.Symlinks
+	}
+	if fileCfg.CABundle != "" {
+		c.CABundle = fileCfg.CABundle
+	}
+	if fileCfg.PasswordsFile != "" {
+		c.PasswordsFile = fileCfg.PasswordsFile
+	}
 
 	if fileCfg.ShutdownTimeout != "" {
 		timeout, err := time.ParseDuration(fileCfg.ShutdownTimeout)
@@ -146,16 +351,82 @@ func (c *Config) LoadFromFile() error {
 		c.ShutdownTimeout = timeout
 	}
 
+	if fileCfg.FileTimeout != "" {
+		timeout, err := time.ParseDuration(fileCfg.FileTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid file timeout: %w", err)
+		}
+		c.FileTimeout = timeout
+	}
+
+	if fileCfg.ScanBudget != "" {
+		budget, err := time.ParseDuration(fileCfg.ScanBudget)
+		if err != nil {
+			return fmt.Errorf("invalid scan budget: %w", err)
+		}
+		c.ScanBudget = budget
+	}
+
+	if fileCfg.RescanInterval != "" {
+		interval, err := time.ParseDuration(fileCfg.RescanInterval)
+		if err != nil {
+			return fmt.Errorf("invalid rescan interval: %w", err)
+		}
+		c.RescanInterval = interval
+	}
+
+	if fileCfg.Jitter != "" {
+		jitter, err := time.ParseDuration(fileCfg.Jitter)
+		if err != nil {
+			return fmt.Errorf("invalid jitter: %w", err)
+		}
+		c.Jitter = jitter
+	}
+
 	return nil
 }
 
+// KeystorePasswords returns the configured passwords followed by the
+// non-empty lines of the passwords file.
+func (c *Config) KeystorePasswords() ([]string, error) {
+	passwords := append([]string(nil), c.Passwords...)
+	if c.PasswordsFile == "" {
+		return passwords, nil
+	}
+
+	data, err := os.ReadFile(c.PasswordsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passwords file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line != "" {
+			passwords = append(passwords, line)
+		}
+	}
+	return passwords, nil
+}
+
 func (c *Config) Validate() error {
 	if c.Days < 0 {
 		return fmt.Errorf("days threshold cannot be negative")
 	}
 
-	if !c.Server && len(c.Paths) == 0 {
-		return fmt.Errorf("at least one path must be specified")
+	if !c.Server && len(c.Paths) == 0 && len(c.Endpoints) == 0 && len(c.Pairs) == 0 {
+		return fmt.Errorf("at least one path, endpoint or pair must be specified")
+	}
+
+	for _, endpoint := range c.Endpoints {
+		if endpoint == "" {
+			return fmt.Errorf("empty endpoint specified")
+		}
+	}
+
+	for _, pair := range c.Pairs {
+		if pair == "" {
+			return fmt.Errorf("empty pair specified")
+		}
 	}
 
 	for _, path := range c.Paths {
@@ -164,6 +435,63 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	for path, root := range c.Roots {
+		if strings.Contains(path, "..") {
+			return fmt.Errorf("path traversal detected in root: %s", path)
+		}
+		if root.Days != nil && *root.Days < 0 {
+			return fmt.Errorf("days threshold of root %s cannot be negative", path)
+		}
+	}
+
+	if c.Walkers < 0 {
+		return fmt.Errorf("walkers cannot be negative")
+	}
+
+	if c.Workers < 0 {
+		return fmt.Errorf("workers cannot be negative")
+	}
+
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("max depth cannot be negative")
+	}
+
+	if c.BufferSize < 0 {
+		return fmt.Errorf("buffer size cannot be negative")
+	}
+
+	if c.MaxFileSize < 0 {
+		return fmt.Errorf("max file size cannot be negative")
+	}
+
+	if c.FileTimeout < 0 {
+		return fmt.Errorf("file timeout cannot be negative")
+	}
+
+	if c.ScanBudget < 0 {
+		return fmt.Errorf("scan budget cannot be negative")
+	}
+
+	if c.FilesPerSecond < 0 || c.BytesPerSecond < 0 {
+		return fmt.Errorf("throttling rates cannot be negative")
+	}
+
+	if c.RescanInterval < 0 {
+		return fmt.Errorf("rescan interval cannot be negative")
+	}
+
+	if c.Jitter < 0 {
+		return fmt.Errorf("jitter cannot be negative")
+	}
+
+	if c.Daemon && c.Watch {
+		return fmt.Errorf("daemon and watch modes cannot be combined")
+	}
+
+	if c.Daemon && c.Server {
+		return fmt.Errorf("daemon and server modes cannot be combined")
+	}
+
 	if c.ShutdownTimeout < 0 {
 		return fmt.Errorf("shutdown timeout cannot be negative")
 	}
@@ -24,13 +24,14 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected ShutdownTimeout to be 30s, got %v", cfg.ShutdownTimeout)
 	}
 
-	expectedExt := []string{".pem", ".cer", ".crt", ".key"}
+	expectedExt := []string{".pem", ".cer", ".crt", ".key", ".p12", ".pfx", ".jks", ".jceks", "cacerts", ".p7b", ".p7c", ".crl", ".csr"}
 	if len(cfg.Extensions) != len(expectedExt) {
 		t.Errorf("Expected %d extensions, got %d", len(expectedExt), len(cfg.Extensions))
 	}
 }
 
 func TestValidate(t *testing.T) {
+	negativeDays := -1
 	tests := []struct {
 		name    string
 		cfg     *Config
@@ -72,6 +73,59 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative file size",
+			cfg: &Config{
+				Days:            30,
+				Paths:           []string{"/etc/ssl/certs"},
+				ShutdownTimeout: 30 * time.Second,
+				MaxFileSize:     -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative scan budget",
+			cfg: &Config{
+				Days:            30,
+				Paths:           []string{"/etc/ssl/certs"},
+				ShutdownTimeout: 30 * time.Second,
+				ScanBudget:      -time.Minute,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative root days",
+			cfg: &Config{
+				Days:            30,
+				Paths:           []string{"/etc/ssl/certs"},
+				ShutdownTimeout: 30 * time.Second,
+				Roots:           map[string]Root{"/etc/pki": {Days: &negativeDays}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "daemon and watch",
+			cfg: &Config{
+				Days:            30,
+				Paths:           []string{"/etc/ssl/certs"},
+				ShutdownTimeout: 30 * time.Second,
+				Daemon:          true,
+				Watch:           true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "daemon and server",
+			cfg: &Config{
+				Days:            30,
+				Paths:           []string{"/etc/ssl/certs"},
+				ShutdownTimeout: 30 * time.Second,
+				Daemon:          true,
+				Server:          true,
+				Port:            8080,
+			},
+			wantErr: true,
+		},
 		{
 			name: "negative timeout",
 			cfg: &Config{
@@ -103,7 +157,18 @@ func TestLoadFromFile(t *testing.T) {
 		"includeSubject": true,
 		"sendTo": "http://example.com",
 		"shutdownTimeout": "60s",
-		"extensions": [".custom"]
+		"extensions": [".custom"],
+		"exclude": ["*.old"],
+		"workers": 2,
+		"maxFileSize": 1048576,
+		"scanBudget": "5m",
+		"filesPerSecond": 50,
+		"lowPriority": true,
+		"watch": true,
+		"rescanInterval": "6h",
+		"schedule": "0 3 * * *",
+		"jitter": "15m",
+		"roots": {"/custom/path/k8s": {"days": 7, "extensions": [".crt"]}}
 	}`
 
 	err := os.WriteFile(configFile, []byte(configData), 0644)
@@ -138,6 +203,80 @@ func TestLoadFromFile(t *testing.T) {
 	if cfg.ShutdownTimeout != 60*time.Second {
 		t.Errorf("Expected ShutdownTimeout to be 60s, got %v", cfg.ShutdownTimeout)
 	}
+
+	if len(cfg.Exclude) != 1 || cfg.Exclude[0] != "*.old" {
+		t.Errorf("Expected exclude [*.old], got %v", cfg.Exclude)
+	}
+
+	if root := cfg.Roots["/custom/path/k8s"]; root.Days == nil || *root.Days != 7 || len(root.Extensions) != 1 {
+		t.Errorf("Expected a 7-day root with one extension, got %+v", cfg.Roots)
+	}
+
+	if cfg.Workers != 2 || cfg.MaxFileSize != 1048576 || cfg.ScanBudget != 5*time.Minute {
+		t.Errorf("Expected 2 workers, 1MB files and a 5m budget, got %d, %d and %v", cfg.Workers, cfg.MaxFileSize, cfg.ScanBudget)
+	}
+
+	if cfg.FilesPerSecond != 50 || cfg.BytesPerSecond != 0 || !cfg.LowPriority {
+		t.Errorf("Expected 50 files per second at low priority, got %v, %d and %t", cfg.FilesPerSecond, cfg.BytesPerSecond, cfg.LowPriority)
+	}
+
+	if !cfg.Watch || cfg.RescanInterval != 6*time.Hour {
+		t.Errorf("Expected watch mode with a 6h rescan interval, got %t and %v", cfg.Watch, cfg.RescanInterval)
+	}
+
+	if cfg.Schedule != "0 3 * * *" || cfg.Jitter != 15*time.Minute {
+		t.Errorf("Expected a daily schedule with 15m of jitter, got %q and %v", cfg.Schedule, cfg.Jitter)
+	}
+
+	if cfg.MaxDepth != 20 || cfg.FileTimeout != time.Minute {
+		t.Errorf("Expected default depth and file timeout, got %d and %v", cfg.MaxDepth, cfg.FileTimeout)
+	}
+}
+
+func TestLoadFromFileTopLevelOnly(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"maxDepth": 0}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg := New()
+	cfg.ConfigFile = configFile
+	if err := cfg.LoadFromFile(); err != nil {
+		t.Fatalf("LoadFromFile() failed: %v", err)
+	}
+	if cfg.MaxDepth != 0 {
+		t.Errorf("Expected a max depth of 0, got %d", cfg.MaxDepth)
+	}
+}
+
+func TestKeystorePasswords(t *testing.T) {
+	tempDir := t.TempDir()
+	passwordsFile := filepath.Join(tempDir, "passwords")
+
+	err := os.WriteFile(passwordsFile, []byte("changeit\r\n\nwith space \n"), 0600)
+	if err != nil {
+		t.Fatalf("Failed to write passwords file: %v", err)
+	}
+
+	cfg := New()
+	cfg.Passwords = []string{"secret"}
+	cfg.PasswordsFile = passwordsFile
+
+	passwords, err := cfg.KeystorePasswords()
+	if err != nil {
+		t.Fatalf("KeystorePasswords() failed: %v", err)
+	}
+
+	expected := []string{"secret", "changeit", "with space "}
+	if len(passwords) != len(expected) {
+		t.Fatalf("Expected %d passwords, got %d", len(expected), len(passwords))
+	}
+
+	for i, password := range expected {
+		if passwords[i] != password {
+			t.Errorf("Expected password %d to be %q, got %q", i, password, passwords[i])
+		}
+	}
 }
 
 func TestInvalidJSON(t *testing.T) {
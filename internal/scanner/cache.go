@@ -0,0 +1,255 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"padecer/internal/config"
+)
+
+const (
+	cacheVersion = 2
+	// Files modified this recently may change again within the resolution
+	// of their mtime, so they are not cached.
+	cacheMinAge = 2 * time.Second
+)
+
+// Cache remembers what was parsed from each file, so that files unchanged
+// since the previous run are not read again. A file is unchanged when its
+// device, inode, size and mtime are. Only the expiry state is recomputed,
+// from the cached dates.
+type Cache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	hits    int
+	misses  int
+}
+
+type cacheEntry struct {
+	Dev          uint64             `json:"dev"`
+	Ino          uint64             `json:"ino"`
+	Size         int64              `json:"size"`
+	ModTime      int64              `json:"mtime"`
+	Settings     string             `json:"settings"`
+	Objects      []string           `json:"objects"`
+	Certs        []*CertificateInfo `json:"certs,omitempty"`
+	Keys         []*KeyInfo         `json:"keys,omitempty"`
+	KeyContainer bool               `json:"keyContainer,omitempty"`
+
+	used bool // looked up or stored since the last Save
+}
+
+type cacheFile struct {
+	Version int                    `json:"version"`
+	Entries map[string]*cacheEntry `json:"entries"`
+}
+
+// OpenCache loads the cache at path. A missing, unreadable or corrupted
+// cache file is not an error: the cache then starts empty.
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]*cacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var file cacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != cacheVersion {
+		config.Log.Warn("Ignoring invalid cache", "path", path, "error", err, "version", file.Version)
+		return c, nil
+	}
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c, nil
+}
+
+// Stats returns the number of files found in and missing from the cache
+// since the last Save.
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Save writes the entries used since the previous Save, dropping files that
+// were not scanned. The file is replaced atomically, so a crash leaves
+// either the old or the new cache.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	file := cacheFile{Version: cacheVersion, Entries: make(map[string]*cacheEntry)}
+	for path, entry := range c.entries {
+		if entry.used {
+			file.Entries[path] = entry
+			entry.used = false
+		}
+	}
+	c.entries = file.Entries
+	c.hits, c.misses = 0, 0
+	data, err := json.Marshal(file)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	return writeFileAtomic(c.path, data, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, syncs it
+// and renames it over path.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp) // fails once renamed
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	// Persist the rename; directories cannot be synced on every platform
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// SetCache makes the parser reuse what it parsed from unchanged files. The
+// cache is not used with chain verification, which needs the certificates
+// themselves, nor for containers opened with one of the passwords.
+func (p *Parser) SetCache(c *Cache) {
+	p.cache = c
+}
+
+// settings fingerprints the options that change what is parsed from a
+// file, so that changing them invalidates the cache.
+func (p *Parser) settings() string {
+	rules := make([]string, 0, len(p.lintRules))
+	for rule := range p.lintRules {
+		rules = append(rules, rule)
+	}
+	slices.Sort(rules)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "subject=%t\nlint=%s\n", p.includeSubject, strings.Join(rules, ","))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// cached returns the contents of fp from the cache if fi matches the entry.
+func (p *Parser) cached(fp string, fi fs.FileInfo) (*fileContents, bool) {
+	if p.cache == nil || p.verifier != nil {
+		return nil, false
+	}
+	key, _ := fileKeyOf(fi)
+	settings := p.settings()
+
+	p.cache.mu.Lock()
+	entry, ok := p.cache.entries[fp]
+	if !ok || entry.Dev != key.dev || entry.Ino != key.ino || entry.Size != fi.Size() ||
+		entry.ModTime != fi.ModTime().UnixNano() || entry.Settings != settings {
+		p.cache.misses++
+		p.cache.mu.Unlock()
+		return nil, false
+	}
+	entry.used = true
+	p.cache.hits++
+	p.cache.mu.Unlock()
+
+	// Entries are shared between scans: hand out copies
+	contents := &fileContents{objects: entry.Objects, keys: entry.Keys, keyContainer: entry.KeyContainer}
+	now := time.Now()
+	for _, cached := range entry.Certs {
+		info := *cached
+		info.Findings = nil
+		for _, f := range cached.Findings {
+			finding := *f
+			finding.DaysUntilExpiry = daysUntil(now, finding.ExpirationDate)
+			info.Findings = append(info.Findings, &finding)
+		}
+		p.refreshState(&info, now)
+		contents.certs = append(contents.certs, &info)
+	}
+	return contents, true
+}
+
+// store caches what was parsed from fp. Files with unparsable blocks are not
+// cached, so that their errors are reported on every run, nor are containers
+// opened with a password, so that nothing derived from the passwords is
+// written to disk.
+func (p *Parser) store(fp string, fi fs.FileInfo, contents *fileContents) {
+	if p.cache == nil || p.verifier != nil || len(contents.errors) > 0 || contents.locked || time.Since(fi.ModTime()) < cacheMinAge {
+		return
+	}
+	key, _ := fileKeyOf(fi)
+	entry := &cacheEntry{
+		Dev:          key.dev,
+		Ino:          key.ino,
+		Size:         fi.Size(),
+		ModTime:      fi.ModTime().UnixNano(),
+		Settings:     p.settings(),
+		Objects:      contents.objects,
+		Certs:        contents.certs,
+		Keys:         contents.keys,
+		KeyContainer: contents.keyContainer,
+		used:         true,
+	}
+
+	p.cache.mu.Lock()
+	p.cache.entries[fp] = entry
+	p.cache.mu.Unlock()
+}
+
+// refreshState recomputes the time-dependent fields of a cached certificate
+// or CRL. CSRs have no validity.
+func (p *Parser) refreshState(info *CertificateInfo, now time.Time) {
+	if info.ExpirationDate.IsZero() {
+		return
+	}
+	days := daysUntil(now, info.ExpirationDate)
+	info.DaysUntilExpiry = days
+	info.IsExpired = info.ExpirationDate.Before(now)
+	info.IsExpiringSoon = days <= p.daysThreshold && days >= 0
+	info.State = certState(now, info.NotBefore, info.ExpirationDate, p.daysThreshold)
+}
+
+func daysUntil(now, t time.Time) int {
+	if t.IsZero() {
+		return 0
+	}
+	return int(t.Sub(now).Hours() / 24)
+}
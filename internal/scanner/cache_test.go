@@ -0,0 +1,130 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache.json")
+	certPath := filepath.Join(dir, "tls.crt")
+	old := time.Now().Add(-time.Hour)
+
+	writeCert := func(cn string, mtime time.Time) {
+		writeTestPEM(t, certPath, issueTestCert(t, cn, false, time.Now().AddDate(0, 0, 20), nil))
+		if err := os.Chtimes(certPath, mtime, mtime); err != nil {
+			t.Fatalf("Chtimes() failed: %v", err)
+		}
+	}
+	parse := func(days int) (*CertificateInfo, int) {
+		t.Helper()
+		cache, err := OpenCache(cachePath)
+		if err != nil {
+			t.Fatalf("OpenCache() failed: %v", err)
+		}
+		p := NewParser(true, days)
+		p.SetCache(cache)
+
+		certInfos, err := p.ParseFileWithContext(context.Background(), certPath)
+		if err != nil {
+			t.Fatalf("ParseFileWithContext() failed: %v", err)
+		}
+		hits, _ := cache.Stats()
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+		return certInfos[0], hits
+	}
+
+	writeCert("first.example.com", old)
+	if info, hits := parse(30); hits != 0 || info.State != StateExpiring {
+		t.Errorf("First run: %d hits, state %s", hits, info.State)
+	}
+
+	// The expiry state is recomputed with the threshold of the run
+	info, hits := parse(10)
+	if hits != 1 || info.Subject != "CN=first.example.com" || info.State != StateValid || info.DaysUntilExpiry != 19 {
+		t.Errorf("Second run: %d hits, %+v", hits, info)
+	}
+
+	// A rewritten file is parsed again, even with the same size
+	writeCert("other.example.com", old.Add(time.Second))
+	if info, hits := parse(30); hits != 0 || info.Subject != "CN=other.example.com" {
+		t.Errorf("Changed file: %d hits, subject %s", hits, info.Subject)
+	}
+
+	// Files that may still be changing are not cached
+	writeCert("recent.example.com", time.Now())
+	parse(30)
+	if _, hits := parse(30); hits != 0 {
+		t.Errorf("Recently modified file cached")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the certificate and the cache, got %d files", len(entries))
+	}
+}
+
+func TestOpenCacheCorrupted(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(cachePath, []byte(`{"version":1,"entries":{`), 0600); err != nil {
+		t.Fatalf("Failed to write cache: %v", err)
+	}
+
+	cache, err := OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache() failed: %v", err)
+	}
+	if len(cache.entries) != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", len(cache.entries))
+	}
+}
+
+func TestCachePasswords(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache.json")
+	old := time.Now().Add(-time.Hour)
+	for _, name := range []string{"empty.p12", "modern.p12"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+		fp := filepath.Join(dir, name)
+		if err := os.WriteFile(fp, data, 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(fp, old, old); err != nil {
+			t.Fatalf("Chtimes() failed: %v", err)
+		}
+	}
+
+	// Only the keystore that needs no password is cached
+	for run := range 2 {
+		cache, err := OpenCache(cachePath)
+		if err != nil {
+			t.Fatalf("OpenCache() failed: %v", err)
+		}
+		p := NewParser(false, 30)
+		p.SetPasswords([]string{"secret"})
+		p.SetCache(cache)
+		for _, name := range []string{"empty.p12", "modern.p12"} {
+			if _, err := p.ParseFile(filepath.Join(dir, name)); err != nil {
+				t.Fatalf("ParseFile(%s) failed: %v", name, err)
+			}
+		}
+		if hits, misses := cache.Stats(); hits != run || misses != 2-run {
+			t.Errorf("Run %d: %d hits, %d misses", run, hits, misses)
+		}
+		if err := cache.Save(); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"padecer/internal/config"
+)
+
+// FindingChainProblem reports the chain problem of a certificate that is not
+// alerted, since alerts already carry the chain.
+const FindingChainProblem = "chain-problem"
+
+// ChainStatus is the outcome of verifying a leaf certificate's chain.
+type ChainStatus string
+
+const (
+	ChainValid      ChainStatus = "valid"
+	ChainIncomplete ChainStatus = "incomplete" // an issuer is missing, usually an intermediate
+	ChainBroken     ChainStatus = "broken"
+)
+
+// ChainInfo is the result of chain verification for a leaf certificate.
+type ChainInfo struct {
+	Status ChainStatus `json:"status"`
+	Error  string      `json:"error,omitempty"`
+
+	// EffectiveExpiry is the earliest NotAfter along the verified path.
+	EffectiveExpiry time.Time `json:"effectiveExpiry,omitzero"`
+
+	// ExpiringBeforeLeaf lists the subjects of issuers on the path that
+	// expire before the leaf does.
+	ExpiringBeforeLeaf []string `json:"expiringBeforeLeaf,omitempty"`
+}
+
+// Problem describes what is wrong with the chain, or returns "" when it is
+// valid and no issuer expires before the leaf.
+func (c *ChainInfo) Problem() string {
+	switch {
+	case c.Status != ChainValid:
+		return fmt.Sprintf("%s chain: %s", c.Status, c.Error)
+	case len(c.ExpiringBeforeLeaf) > 0:
+		return fmt.Sprintf("issuer expires before the leaf, chain valid until %s", c.EffectiveExpiry.Format("2006-01-02"))
+	default:
+		return ""
+	}
+}
+
+// verifier holds the trust roots and the intermediates found next to the
+// files being scanned, loaded once per directory.
+type verifier struct {
+	roots *x509.CertPool // nil for the system pool
+
+	mu   sync.Mutex
+	dirs map[string]*dirCerts
+}
+
+type dirCerts struct {
+	mu     sync.Mutex
+	loaded bool
+	certs  []*x509.Certificate
+}
+
+// LoadRoots reads a PEM bundle of trusted CA certificates.
+func LoadRoots(fp string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", fp)
+	}
+	return pool, nil
+}
+
+// SetVerification enables chain verification of leaf certificates against
+// roots, or the system pool when roots is nil.
+func (p *Parser) SetVerification(roots *x509.CertPool) {
+	p.verifier = &verifier{roots: roots, dirs: make(map[string]*dirCerts)}
+}
+
+// scanCtxKey holds the context of the whole scan in the context of each
+// file, for the work shared by the files of a directory.
+type scanCtxKey struct{}
+
+// withScanContext makes scan the context of the work that fileCtx shares
+// with other files, so that it is not cut short by the timeout of one file.
+func withScanContext(fileCtx, scan context.Context) context.Context {
+	return context.WithValue(fileCtx, scanCtxKey{}, scan)
+}
+
+func scanContext(ctx context.Context) context.Context {
+	if scan, ok := ctx.Value(scanCtxKey{}).(context.Context); ok {
+		return scan
+	}
+	return ctx
+}
+
+// verifyFile verifies the leaves of a file using the certificates of the
+// same file and the CA certificates of its directory as intermediates. When
+// the directory could not be read in full, the chains are left unverified
+// rather than reported incomplete for want of an intermediate.
+func (p *Parser) verifyFile(ctx context.Context, fp string, certInfos []*CertificateInfo) {
+	intermediates, ok := p.verifier.dirIntermediates(scanContext(ctx), p, filepath.Dir(fp))
+	if !ok {
+		config.Log.Warn("Chain not verified, intermediates not loaded", "path", fp)
+		return
+	}
+	for _, info := range certInfos {
+		if info.cert != nil {
+			intermediates = append(intermediates, info.cert)
+		}
+	}
+	p.verifyChains(certInfos, intermediates)
+}
+
+func (p *Parser) verifyChains(certInfos []*CertificateInfo, intermediates []*x509.Certificate) {
+	for _, info := range certInfos {
+		if info.cert == nil || info.cert.IsCA {
+			continue
+		}
+		info.Chain = p.verifier.verify(info.cert, intermediates)
+		if problem := info.Chain.Problem(); problem != "" && !info.NeedsAlert() {
+			info.Findings = append(info.Findings, &Finding{
+				Type:            FindingChainProblem,
+				Severity:        chainSeverity(info.Chain),
+				Path:            info.Path,
+				Alias:           info.Alias,
+				Message:         problem,
+				ExpirationDate:  info.ExpirationDate,
+				DaysUntilExpiry: info.DaysUntilExpiry,
+				SerialNumber:    info.SerialNumber,
+			})
+		}
+	}
+}
+
+func chainSeverity(chain *ChainInfo) Severity {
+	if chain.Status == ChainBroken {
+		return SeverityCritical
+	}
+	return SeverityWarn
+}
+
+// dirIntermediates returns the CA certificates found in the files of dir
+// that p selects, read once per directory, and false if the read was cut
+// short by ctx. Such a read is not kept, so that the next file of the
+// directory tries again.
+func (v *verifier) dirIntermediates(ctx context.Context, p *Parser, dir string) ([]*x509.Certificate, bool) {
+	v.mu.Lock()
+	d, ok := v.dirs[dir]
+	if !ok {
+		d = &dirCerts{}
+		v.dirs[dir] = d
+	}
+	v.mu.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.loaded {
+		return append([]*x509.Certificate(nil), d.certs...), true
+	}
+
+	var certs []*x509.Certificate
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+
+		fp := filepath.Join(dir, entry.Name())
+		if p.selectFile != nil && !p.selectFile(fp) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() > p.fileSizeLimit() {
+			continue
+		}
+
+		data, err := p.readFileWithContext(ctx, fp)
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		if err != nil {
+			continue
+		}
+
+		// Bad blocks do not hide the CA certificates around them
+		certInfos, err := p.ParseData(fp, data)
+		if err != nil {
+			config.Log.Debug("Failed to parse intermediates", "path", fp, "error", err)
+		}
+		for _, info := range certInfos {
+			if info.cert != nil && info.cert.IsCA {
+				certs = append(certs, info.cert)
+			}
+		}
+	}
+
+	d.certs, d.loaded = certs, true
+	return append([]*x509.Certificate(nil), certs...), true
+}
+
+func (v *verifier) verify(leaf *x509.Certificate, intermediates []*x509.Certificate) *ChainInfo {
+	pool := x509.NewCertPool()
+	for _, cert := range intermediates {
+		if cert != leaf {
+			pool.AddCert(cert)
+		}
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: pool,
+		CurrentTime:   verifyTime(leaf),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return chainError(err)
+	}
+
+	// Prefer the path that stays valid the longest.
+	best, bestExpiry := chains[0], effectiveExpiry(chains[0])
+	for _, chain := range chains[1:] {
+		if expiry := effectiveExpiry(chain); expiry.After(bestExpiry) {
+			best, bestExpiry = chain, expiry
+		}
+	}
+
+	info := &ChainInfo{Status: ChainValid, EffectiveExpiry: bestExpiry}
+	for _, cert := range best[1:] {
+		if cert.NotAfter.Before(leaf.NotAfter) {
+			info.ExpiringBeforeLeaf = append(info.ExpiringBeforeLeaf, cert.Subject.String())
+		}
+	}
+	return info
+}
+
+// verifyTime keeps the verification time inside the leaf's validity, so an
+// expired or not yet valid leaf, which is reported on its own, does not hide
+// problems with the rest of its chain.
+func verifyTime(leaf *x509.Certificate) time.Time {
+	now := time.Now()
+	switch {
+	case now.After(leaf.NotAfter):
+		return leaf.NotAfter
+	case now.Before(leaf.NotBefore):
+		return leaf.NotBefore
+	default:
+		return now
+	}
+}
+
+func effectiveExpiry(chain []*x509.Certificate) time.Time {
+	expiry := chain[0].NotAfter
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	return expiry
+}
+
+func chainError(err error) *ChainInfo {
+	var unknown x509.UnknownAuthorityError
+	if errors.As(err, &unknown) && unknown.Cert != nil && !isSelfSigned(unknown.Cert) {
+		return &ChainInfo{
+			Status: ChainIncomplete,
+			Error:  fmt.Sprintf("issuer %q not found", unknown.Cert.Issuer.String()),
+		}
+	}
+	return &ChainInfo{Status: ChainBroken, Error: err.Error()}
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
@@ -0,0 +1,232 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issueTestCert signs a certificate for cn with parent, or self-signs it
+// when parent is nil.
+func issueTestCert(t *testing.T, cn string, isCA bool, notAfter time.Time, parent *testCA) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if isCA {
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func writeTestPEM(t *testing.T, path string, certs ...*testCA) {
+	var data []byte
+	for _, c := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})...)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestChainVerification(t *testing.T) {
+	year := time.Now().AddDate(1, 0, 0)
+	root := issueTestCert(t, "Test Root", true, year.AddDate(10, 0, 0), nil)
+	intermediate := issueTestCert(t, "Test Intermediate", true, year.AddDate(0, -6, 0), root)
+	leaf := issueTestCert(t, "leaf.example.com", false, year, intermediate)
+	selfSigned := issueTestCert(t, "self.example.com", false, year, nil)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	sameFile := t.TempDir()
+	writeTestPEM(t, filepath.Join(sameFile, "fullchain.pem"), leaf, intermediate)
+
+	sameDir := t.TempDir()
+	writeTestPEM(t, filepath.Join(sameDir, "tls.crt"), leaf)
+	writeTestPEM(t, filepath.Join(sameDir, "intermediate.crt"), intermediate)
+
+	alone := t.TempDir()
+	writeTestPEM(t, filepath.Join(alone, "tls.crt"), leaf)
+	writeTestPEM(t, filepath.Join(alone, "self.crt"), selfSigned)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus ChainStatus
+	}{
+		{"intermediate in same file", filepath.Join(sameFile, "fullchain.pem"), ChainValid},
+		{"intermediate in same directory", filepath.Join(sameDir, "tls.crt"), ChainValid},
+		{"missing intermediate", filepath.Join(alone, "tls.crt"), ChainIncomplete},
+		{"untrusted self-signed", filepath.Join(alone, "self.crt"), ChainBroken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(false, 30)
+			p.SetVerification(roots)
+
+			certInfos, err := p.ParseFile(tt.path)
+			if err != nil {
+				t.Fatalf("ParseFile() failed: %v", err)
+			}
+
+			chain := certInfos[0].Chain
+			if chain == nil {
+				t.Fatal("Expected chain information on the leaf")
+			}
+			if chain.Status != tt.wantStatus {
+				t.Fatalf("Expected chain status %q, got %q (%s)", tt.wantStatus, chain.Status, chain.Error)
+			}
+
+			if tt.wantStatus == ChainValid {
+				if !chain.EffectiveExpiry.Equal(intermediate.cert.NotAfter) {
+					t.Errorf("Expected effective expiry %v, got %v", intermediate.cert.NotAfter, chain.EffectiveExpiry)
+				}
+				if len(chain.ExpiringBeforeLeaf) != 1 || chain.ExpiringBeforeLeaf[0] != "CN=Test Intermediate" {
+					t.Errorf("Expected the intermediate to expire before the leaf, got %v", chain.ExpiringBeforeLeaf)
+				}
+			}
+
+			// The leaf is not alerted, so its chain problem is a finding
+			if findings := certInfos[0].Findings; len(findings) != 1 || findings[0].Type != FindingChainProblem {
+				t.Errorf("Expected a %s finding, got %+v", FindingChainProblem, findings)
+			}
+
+			for _, info := range certInfos[1:] {
+				if info.Chain != nil {
+					t.Errorf("Expected CA certificates to be skipped, got %+v", info.Chain)
+				}
+			}
+		})
+	}
+}
+
+func TestScanChainIntermediates(t *testing.T) {
+	year := time.Now().AddDate(1, 0, 0)
+	root := issueTestCert(t, "Test Root", true, year.AddDate(10, 0, 0), nil)
+	intermediate := issueTestCert(t, "Test Intermediate", true, year.AddDate(1, 0, 0), root)
+	leaf := issueTestCert(t, "leaf.example.com", false, year, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	// Only the files selected by the scan provide intermediates
+	dir := t.TempDir()
+	writeTestPEM(t, filepath.Join(dir, "tls.crt"), leaf)
+	writeTestPEM(t, filepath.Join(dir, "intermediate.crt.old"), intermediate)
+
+	for _, tt := range []struct {
+		name       string
+		extensions []string
+		wantStatus ChainStatus
+	}{
+		{"intermediate selected", []string{".crt", ".old"}, ChainValid},
+		{"intermediate not selected", []string{".crt"}, ChainIncomplete},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(false, 30)
+			p.SetVerification(roots)
+			scanner := New(p, shutdown.NewManager(30*time.Second), Options{Extensions: tt.extensions})
+
+			resultCh, err := scanner.Scan(context.Background(), []string{dir})
+			if err != nil {
+				t.Fatalf("Scan() failed: %v", err)
+			}
+
+			var chain *ChainInfo
+			for result := range resultCh {
+				for _, info := range result.CertInfos {
+					if info.Path == filepath.Join(dir, "tls.crt") {
+						chain = info.Chain
+					}
+				}
+			}
+			if chain == nil || chain.Status != tt.wantStatus {
+				t.Errorf("Expected chain status %q, got %+v", tt.wantStatus, chain)
+			}
+		})
+	}
+}
+
+func TestVerifyFileInterrupted(t *testing.T) {
+	year := time.Now().AddDate(1, 0, 0)
+	root := issueTestCert(t, "Test Root", true, year.AddDate(10, 0, 0), nil)
+	intermediate := issueTestCert(t, "Test Intermediate", true, year.AddDate(1, 0, 0), root)
+	leaf := issueTestCert(t, "leaf.example.com", false, year, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tls.crt")
+	writeTestPEM(t, fp, leaf)
+	writeTestPEM(t, filepath.Join(dir, "intermediate.crt"), intermediate)
+
+	parse := func(p *Parser) []*CertificateInfo {
+		t.Helper()
+		certInfos, err := p.ParseData(fp, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.cert.Raw}))
+		if err != nil || len(certInfos) != 1 {
+			t.Fatalf("ParseData() = %d certificates, %v", len(certInfos), err)
+		}
+		return certInfos
+	}
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A directory read cut short leaves the chain unverified rather than
+	// incomplete, and is tried again by the next file
+	p := NewParser(false, 30)
+	p.SetVerification(roots)
+	certInfos := parse(p)
+	p.verifyFile(expired, fp, certInfos)
+	if certInfos[0].Chain != nil || len(certInfos[0].Findings) != 0 {
+		t.Errorf("Expected no chain verification, got %+v", certInfos[0].Chain)
+	}
+
+	// The intermediates are read under the scan context, not the file's
+	certInfos = parse(p)
+	p.verifyFile(withScanContext(expired, context.Background()), fp, certInfos)
+	if chain := certInfos[0].Chain; chain == nil || chain.Status != ChainValid {
+		t.Errorf("Expected a valid chain, got %+v", chain)
+	}
+}
@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrorKind categorizes scan failures, e.g. for the summary of a scan.
+type ErrorKind string
+
+const (
+	ErrorPermissionDenied    ErrorKind = "permission-denied"
+	ErrorNotCertificate      ErrorKind = "not-a-certificate"
+	ErrorTooLarge            ErrorKind = "too-large"
+	ErrorTimeout             ErrorKind = "timeout"
+	ErrorUnreadableDirectory ErrorKind = "unreadable-directory"
+	ErrorDepthExceeded       ErrorKind = "depth-exceeded"
+	ErrorEncryptedContainer  ErrorKind = "encrypted-container"
+	ErrorUnreadableFile      ErrorKind = "unreadable-file"
+	ErrorBudgetExceeded      ErrorKind = "budget-exceeded"
+	ErrorCancelled           ErrorKind = "cancelled"
+)
+
+// errFileTooLarge is returned for files over Limits.MaxFileSize.
+var errFileTooLarge = errors.New("file too large")
+
+// ScanError is a file or directory that could not be scanned. Callers get
+// it with errors.As; the underlying error is still reachable with errors.Is,
+// e.g. ErrNoMatchingPassword.
+type ScanError struct {
+	Kind ErrorKind
+	Op   string // parse, read directory or walk
+	Path string
+	Err  error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("failed to %s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// KindOf returns the kind of a *ScanError in err's chain, or the empty
+// string if there is none.
+func KindOf(err error) ErrorKind {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr.Kind
+	}
+	return ""
+}
+
+// fileError classifies a failure to scan the file at fp.
+func fileError(fp string, err error) *ScanError {
+	kind := ErrorNotCertificate
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		kind = ErrorPermissionDenied
+	case errors.Is(err, context.DeadlineExceeded):
+		kind = ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		kind = ErrorCancelled
+	case errors.Is(err, errFileTooLarge):
+		kind = ErrorTooLarge
+	case errors.Is(err, ErrNoMatchingPassword):
+		kind = ErrorEncryptedContainer
+	case errors.As(err, &pathErr):
+		kind = ErrorUnreadableFile
+	}
+	return &ScanError{Kind: kind, Op: "parse", Path: fp, Err: err}
+}
@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestFileErrorKinds(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{&fs.PathError{Op: "open", Path: "x", Err: fs.ErrPermission}, ErrorPermissionDenied},
+		{&fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}, ErrorUnreadableFile},
+		{fmt.Errorf("timeout: %w", context.DeadlineExceeded), ErrorTimeout},
+		{fmt.Errorf("%w: 200MB", errFileTooLarge), ErrorTooLarge},
+		{fmt.Errorf("pkcs12: %w", ErrNoMatchingPassword), ErrorEncryptedContainer},
+		{errors.New("failed to parse certificate"), ErrorNotCertificate},
+	}
+
+	for _, tt := range tests {
+		err := error(fileError("x", tt.err))
+		var scanErr *ScanError
+		if !errors.As(err, &scanErr) || scanErr.Kind != tt.want {
+			t.Errorf("fileError(%v) kind = %v, want %v", tt.err, KindOf(err), tt.want)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("fileError(%v) does not wrap the original error", tt.err)
+		}
+	}
+
+	if KindOf(errors.New("plain")) != "" {
+		t.Error("Expected no kind for a plain error")
+	}
+}
+
+func TestScanErrorKinds(t *testing.T) {
+	dir := t.TempDir()
+
+	deep := dir
+	for i := 0; i <= MaxDepth+1; i++ {
+		deep = filepath.Join(deep, "d")
+	}
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "garbage.pem"), []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".pem"}})
+	resultCh, err := scanner.Scan(context.Background(), []string{dir, filepath.Join(dir, "missing")})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	kinds := make(map[ErrorKind]string)
+	for result := range resultCh {
+		var scanErr *ScanError
+		if errors.As(result.Error, &scanErr) {
+			kinds[scanErr.Kind] = result.Path
+		}
+	}
+
+	if path := kinds[ErrorNotCertificate]; filepath.Base(path) != "garbage.pem" {
+		t.Errorf("Expected garbage.pem to be %s, got %q", ErrorNotCertificate, path)
+	}
+	if path := kinds[ErrorDepthExceeded]; !strings.HasPrefix(path, dir) {
+		t.Errorf("Expected a %s error, got %v", ErrorDepthExceeded, kinds)
+	}
+	if path := kinds[ErrorUnreadableDirectory]; filepath.Base(path) != "missing" {
+		t.Errorf("Expected the missing directory to be %s, got %q", ErrorUnreadableDirectory, path)
+	}
+}
+
+func TestScanLimits(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(dir, "a", "tls.pem"), issueTestCert(t, "limits.example.com", false, time.Now().AddDate(1, 0, 0), nil))
+
+	scan := func(limits Limits) map[ErrorKind]string {
+		t.Helper()
+		scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".pem"}, Limits: limits})
+		resultCh, err := scanner.Scan(context.Background(), []string{dir})
+		if err != nil {
+			t.Fatalf("Scan() failed: %v", err)
+		}
+		kinds := make(map[ErrorKind]string)
+		for result := range resultCh {
+			if kind := KindOf(result.Error); kind != "" {
+				kinds[kind] = result.Path
+			}
+		}
+		return kinds
+	}
+
+	kinds := scan(Limits{MaxDepth: 1, MaxFileSize: 64, Workers: 1, BufferSize: 1})
+	if path := kinds[ErrorDepthExceeded]; path != filepath.Join(dir, "a", "b") {
+		t.Errorf("Expected a/b to be %s, got %v", ErrorDepthExceeded, kinds)
+	}
+	if path := kinds[ErrorTooLarge]; filepath.Base(path) != "tls.pem" {
+		t.Errorf("Expected tls.pem to be %s, got %v", ErrorTooLarge, kinds)
+	}
+
+	kinds = scan(Limits{MaxDepth: TopLevelOnly})
+	if path := kinds[ErrorDepthExceeded]; path != filepath.Join(dir, "a") {
+		t.Errorf("Expected a to be %s, got %v", ErrorDepthExceeded, kinds)
+	}
+
+	kinds = scan(Limits{ScanBudget: time.Nanosecond})
+	if path := kinds[ErrorBudgetExceeded]; path != dir {
+		t.Errorf("Expected the unwalked path to be %s, got %v", ErrorBudgetExceeded, kinds)
+	}
+}
@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"net"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDescribeCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	spiffe, _ := url.Parse("spiffe://corp.example/web")
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(42),
+		Subject:        pkix.Name{CommonName: "*.corp.example"},
+		NotBefore:      time.Now().Add(-time.Hour),
+		NotAfter:       time.Now().AddDate(0, 3, 0),
+		DNSNames:       []string{"*.corp.example", "corp.example"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"ops@corp.example"},
+		URIs:           []*url.URL{spiffe},
+		KeyUsage:       x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		SubjectKeyId:   []byte{0x01, 0x02, 0x03},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+
+	info := NewParser(false, 30).buildCertificateInfo("inventory.pem", cert)
+
+	if !slices.Equal(info.DNSNames, template.DNSNames) {
+		t.Errorf("DNSNames = %v, want %v", info.DNSNames, template.DNSNames)
+	}
+	if !slices.Equal(info.IPAddresses, []string{"10.0.0.1"}) {
+		t.Errorf("IPAddresses = %v", info.IPAddresses)
+	}
+	if !slices.Equal(info.EmailAddresses, template.EmailAddresses) {
+		t.Errorf("EmailAddresses = %v", info.EmailAddresses)
+	}
+	if !slices.Equal(info.URIs, []string{"spiffe://corp.example/web"}) {
+		t.Errorf("URIs = %v", info.URIs)
+	}
+
+	if info.KeyAlgorithm != "ECDSA" || info.KeySize != 384 {
+		t.Errorf("Key = %s %d, want ECDSA 384", info.KeyAlgorithm, info.KeySize)
+	}
+	if info.SignatureAlgorithm != "ECDSA-SHA384" {
+		t.Errorf("SignatureAlgorithm = %s", info.SignatureAlgorithm)
+	}
+	if !slices.Equal(info.KeyUsage, []string{"digitalSignature", "keyEncipherment"}) {
+		t.Errorf("KeyUsage = %v", info.KeyUsage)
+	}
+	if !slices.Equal(info.ExtKeyUsage, []string{"serverAuth", "clientAuth"}) {
+		t.Errorf("ExtKeyUsage = %v", info.ExtKeyUsage)
+	}
+
+	if info.SubjectKeyID != "010203" {
+		t.Errorf("SubjectKeyID = %s, want 010203", info.SubjectKeyID)
+	}
+	if info.IsCA {
+		t.Error("Expected a leaf certificate")
+	}
+
+	sum := sha1.Sum(der)
+	if info.FingerprintSHA1 != hex.EncodeToString(sum[:]) {
+		t.Errorf("FingerprintSHA1 = %s", info.FingerprintSHA1)
+	}
+	if len(info.FingerprintSHA256) != 64 {
+		t.Errorf("FingerprintSHA256 = %s", info.FingerprintSHA256)
+	}
+}
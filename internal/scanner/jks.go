@@ -0,0 +1,168 @@
+package scanner
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	jksMagic   = 0xFEEDFEED
+	jceksMagic = 0xCECECECE
+
+	jksPrivateKeyTag  = 1
+	jksTrustedCertTag = 2
+	jksSecretKeyTag   = 3
+)
+
+var errShortKeystore = errors.New("jks: unexpected end of data")
+
+// keystoreEntry is a JKS/JCEKS entry with the certificates stored under its
+// alias: the whole chain for a private key entry, one certificate otherwise.
+type keystoreEntry struct {
+	alias string
+	certs []*x509.Certificate
+	key   bool // a private or secret key entry
+}
+
+// isJKS reports whether data starts with the JKS or JCEKS magic number.
+func isJKS(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	magic := binary.BigEndian.Uint32(data)
+	return magic == jksMagic || magic == jceksMagic
+}
+
+func (p *Parser) parseJKS(fp string, data []byte, contents *fileContents) error {
+	entries, err := decodeJKS(data)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		for _, cert := range entry.certs {
+			info := p.buildCertificateInfo(fp, cert)
+			info.Alias = entry.alias
+			contents.certs = append(contents.certs, info)
+		}
+		contents.keyContainer = contents.keyContainer || entry.key
+	}
+
+	if len(contents.certs) == 0 {
+		return fmt.Errorf("no certificates found in file")
+	}
+	return nil
+}
+
+// decodeJKS lists the certificate-bearing entries of a Java keystore.
+// Certificates are stored in clear text, so no password is needed; the
+// keystore integrity digest is not verified.
+func decodeJKS(data []byte) ([]keystoreEntry, error) {
+	r := &jksReader{data: data}
+
+	magic := r.uint32()
+	version := r.uint32()
+	count := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if magic != jksMagic && magic != jceksMagic {
+		return nil, fmt.Errorf("jks: invalid magic number %#x", magic)
+	}
+	if version != 1 && version != 2 {
+		return nil, fmt.Errorf("jks: unsupported version %d", version)
+	}
+
+	var entries []keystoreEntry
+	for i := uint32(0); i < count; i++ {
+		tag := r.uint32()
+		alias := r.utf()
+		r.skip(8) // creation timestamp
+
+		entry := keystoreEntry{alias: alias, key: tag != jksTrustedCertTag}
+		switch tag {
+		case jksPrivateKeyTag:
+			r.skip(int(r.uint32())) // encrypted private key
+			chain := r.uint32()
+			for j := uint32(0); j < chain && r.err == nil; j++ {
+				entry.certs = append(entry.certs, r.cert(version))
+			}
+		case jksTrustedCertTag:
+			entry.certs = append(entry.certs, r.cert(version))
+		case jksSecretKeyTag:
+			// JCEKS secret keys are serialized Java objects whose length
+			// is not recorded, so nothing after them can be located.
+			return append(entries, entry), nil
+		default:
+			return nil, fmt.Errorf("jks: unknown entry tag %d", tag)
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+type jksReader struct {
+	data []byte
+	err  error
+}
+
+func (r *jksReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.err = errShortKeystore
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *jksReader) skip(n int) {
+	r.next(n)
+}
+
+func (r *jksReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *jksReader) utf() string {
+	b := r.next(2)
+	if b == nil {
+		return ""
+	}
+	return string(r.next(int(binary.BigEndian.Uint16(b))))
+}
+
+func (r *jksReader) cert(version uint32) *x509.Certificate {
+	if version == 2 {
+		if certType := r.utf(); r.err == nil && certType != "X.509" {
+			r.err = fmt.Errorf("jks: unsupported certificate type %q", certType)
+			return nil
+		}
+	}
+
+	der := r.next(int(r.uint32()))
+	if r.err != nil {
+		return nil
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		r.err = fmt.Errorf("jks: %w", err)
+		return nil
+	}
+	return cert
+}
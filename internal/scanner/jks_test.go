@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+type testJKSEntry struct {
+	tag   uint32
+	alias string
+	certs [][]byte
+}
+
+func buildTestJKS(magic, version uint32, entries []testJKSEntry) []byte {
+	var buf bytes.Buffer
+	write := func(v any) { binary.Write(&buf, binary.BigEndian, v) }
+	writeUTF := func(s string) {
+		write(uint16(len(s)))
+		buf.WriteString(s)
+	}
+	writeCert := func(der []byte) {
+		if version == 2 {
+			writeUTF("X.509")
+		}
+		write(uint32(len(der)))
+		buf.Write(der)
+	}
+
+	write(magic)
+	write(version)
+	write(uint32(len(entries)))
+	for _, e := range entries {
+		write(e.tag)
+		writeUTF(e.alias)
+		write(time.Now().UnixMilli())
+		switch e.tag {
+		case jksPrivateKeyTag:
+			key := []byte("opaque encrypted key")
+			write(uint32(len(key)))
+			buf.Write(key)
+			write(uint32(len(e.certs)))
+			for _, der := range e.certs {
+				writeCert(der)
+			}
+		case jksTrustedCertTag:
+			writeCert(e.certs[0])
+		}
+	}
+
+	buf.Write(make([]byte, 20)) // integrity digest, not verified
+	return buf.Bytes()
+}
+
+func testCertDER(t *testing.T, notAfter time.Time) []byte {
+	block, _ := pem.Decode(generateTestCert(t, notAfter))
+	return block.Bytes
+}
+
+func TestJKS(t *testing.T) {
+	leaf := testCertDER(t, time.Now().Add(10*24*time.Hour))
+	issuer := testCertDER(t, time.Now().Add(365*24*time.Hour))
+	root := testCertDER(t, time.Now().Add(3650*24*time.Hour))
+
+	entries := []testJKSEntry{
+		{jksPrivateKeyTag, "tomcat", [][]byte{leaf, issuer}},
+		{jksTrustedCertTag, "rootca", [][]byte{root}},
+	}
+
+	tests := []struct {
+		name    string
+		magic   uint32
+		version uint32
+	}{
+		{"JKS v2", jksMagic, 2},
+		{"JKS v1", jksMagic, 1},
+		{"JCEKS", jceksMagic, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(false, 30)
+			data := buildTestJKS(tt.magic, tt.version, entries)
+
+			certInfos, err := p.ParseData("keystore.jks", data)
+			if err != nil {
+				t.Fatalf("ParseData() failed: %v", err)
+			}
+
+			if len(certInfos) != 3 {
+				t.Fatalf("Expected 3 certificates, got %d", len(certInfos))
+			}
+
+			wantAliases := []string{"tomcat", "tomcat", "rootca"}
+			for i, alias := range wantAliases {
+				if certInfos[i].Alias != alias {
+					t.Errorf("Expected certificate %d alias %q, got %q", i, alias, certInfos[i].Alias)
+				}
+			}
+
+			if !certInfos[0].IsExpiringSoon {
+				t.Errorf("Leaf certificate of tomcat entry should be expiring soon")
+			}
+		})
+	}
+}
+
+func TestTruncatedJKS(t *testing.T) {
+	p := NewParser(false, 30)
+	root := testCertDER(t, time.Now().Add(365*24*time.Hour))
+	data := buildTestJKS(jksMagic, 2, []testJKSEntry{{jksTrustedCertTag, "rootca", [][]byte{root}}})
+
+	_, err := p.ParseData("cacerts", data[:len(data)/2])
+	if err == nil {
+		t.Errorf("Expected error for truncated keystore, got nil")
+	}
+}
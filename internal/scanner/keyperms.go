@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Findings raised by the private key file audit.
+const (
+	FindingKeyPermissions = "insecure-key-permissions"
+	FindingKeyOwner       = "unexpected-key-owner"
+)
+
+// auditKeyFile checks that the private key file fp is not readable by group
+// or others and, when allowed owners are configured, that one of them owns
+// it.
+func (s *Scanner) auditKeyFile(fp string) []*Finding {
+	fi, err := os.Stat(fp)
+	if err != nil {
+		return nil
+	}
+
+	var findings []*Finding
+	if perm := fi.Mode().Perm(); modeBitsEnforced && perm&0o044 != 0 {
+		severity := SeverityWarn
+		if perm&0o004 != 0 {
+			severity = SeverityCritical
+		}
+		findings = append(findings, &Finding{
+			Type:     FindingKeyPermissions,
+			Severity: severity,
+			Path:     fp,
+			Message:  fmt.Sprintf("private key mode %04o is readable by group or others", perm),
+		})
+	}
+
+	if len(s.keyOwners) > 0 {
+		if uid, name, ok := fileOwner(fi); ok && !slices.Contains(s.keyOwners, uid) && !slices.Contains(s.keyOwners, name) {
+			findings = append(findings, &Finding{
+				Type:     FindingKeyOwner,
+				Severity: SeverityWarn,
+				Path:     fp,
+				Message:  fmt.Sprintf("private key owned by %s (uid %s)", name, uid),
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+const modeBitsEnforced = true
+
+// fileOwner returns the uid and user name owning fi, the uid again when it
+// has no name.
+func fileOwner(fi os.FileInfo) (uid, name string, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", false
+	}
+
+	uid = strconv.FormatUint(uint64(st.Uid), 10)
+	name = uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	return uid, name, true
+}
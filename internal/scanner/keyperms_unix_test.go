@@ -0,0 +1,113 @@
+//go:build unix
+
+package scanner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestAuditKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(fp, []byte("key"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mode     os.FileMode
+		owners   []string
+		want     string
+		severity Severity
+	}{
+		{"private", 0600, nil, "", ""},
+		{"group readable", 0640, nil, FindingKeyPermissions, SeverityWarn},
+		{"world readable", 0644, nil, FindingKeyPermissions, SeverityCritical},
+		{"allowed owner", 0600, []string{strconv.Itoa(os.Getuid())}, "", ""},
+		{"unexpected owner", 0600, []string{"padecer-nobody"}, FindingKeyOwner, SeverityWarn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chmod(fp, tt.mode); err != nil {
+				t.Fatalf("Failed to chmod key: %v", err)
+			}
+
+			s := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{KeyOwners: tt.owners})
+			findings := s.auditKeyFile(fp)
+
+			if tt.want == "" {
+				if len(findings) != 0 {
+					t.Errorf("Expected no findings, got %+v", findings[0])
+				}
+				return
+			}
+			if len(findings) != 1 || findings[0].Type != tt.want || findings[0].Severity != tt.severity {
+				t.Fatalf("Expected one %s %s finding, got %d", tt.severity, tt.want, len(findings))
+			}
+		})
+	}
+}
+
+func TestAuditKeyContainers(t *testing.T) {
+	p12, err := os.ReadFile(filepath.Join("testdata", "empty.p12"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	cert := testCertDER(t, time.Now().AddDate(1, 0, 0))
+	certData := base64.StdEncoding.EncodeToString(generateTestCert(t, time.Now().AddDate(1, 0, 0)))
+	kubeconfig := func(user string) []byte {
+		return []byte(fmt.Sprintf("apiVersion: v1\nclusters:\n- cluster:\n    certificate-authority-data: %s\n  name: kubernetes\nusers:\n- name: admin\n  user:\n%s", certData, user))
+	}
+
+	// Only the containers holding keys are audited
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"keystore.p12":   p12,
+		"keystore.jks":   buildTestJKS(jksMagic, 2, []testJKSEntry{{jksPrivateKeyTag, "tomcat", [][]byte{cert}}}),
+		"truststore.jks": buildTestJKS(jksMagic, 2, []testJKSEntry{{jksTrustedCertTag, "rootca", [][]byte{cert}}}),
+		"admin.conf":     kubeconfig("    client-certificate-data: " + certData + "\n    client-key-data: c2VjcmV0\n"),
+		"ca.conf":        kubeconfig("    token: secret\n"),
+	}
+	for name, data := range files {
+		fp := filepath.Join(dir, name)
+		if err := os.WriteFile(fp, data, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if err := os.Chmod(fp, 0644); err != nil {
+			t.Fatalf("Failed to chmod %s: %v", name, err)
+		}
+	}
+
+	s := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".p12", ".jks", ".conf"}})
+	resultCh, err := s.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var audited []string
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		for _, finding := range result.Findings {
+			if finding.Type == FindingKeyPermissions {
+				audited = append(audited, filepath.Base(finding.Path))
+			}
+		}
+	}
+	slices.Sort(audited)
+	if want := []string{"admin.conf", "keystore.jks", "keystore.p12"}; !slices.Equal(audited, want) {
+		t.Errorf("Expected findings on %v, got %v", want, audited)
+	}
+}
@@ -0,0 +1,270 @@
+package scanner
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Findings raised when pairing private keys with certificates.
+const (
+	FindingOrphanKey   = "orphan-private-key"
+	FindingMissingKey  = "missing-private-key"
+	FindingKeyMismatch = "key-certificate-mismatch"
+)
+
+// KeyInfo describes a private key found while scanning. Encrypted keys are
+// detected but not decrypted, so they carry no PublicKeyID.
+type KeyInfo struct {
+	Path        string `json:"path"`
+	Algorithm   string `json:"algorithm,omitempty"`
+	Format      string `json:"format"` // pkcs1, pkcs8 or sec1
+	Encrypted   bool   `json:"encrypted"`
+	PublicKeyID string `json:"publicKeyId,omitempty"`
+}
+
+func isPrivateKeyBlock(blockType string) bool {
+	switch blockType {
+	case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY", "ENCRYPTED PRIVATE KEY":
+		return true
+	default:
+		return false
+	}
+}
+
+func parsePrivateKeyBlock(fp string, block *pem.Block) (*KeyInfo, error) {
+	info := &KeyInfo{Path: fp}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		info.Format, info.Algorithm = "pkcs1", "RSA"
+	case "EC PRIVATE KEY":
+		info.Format, info.Algorithm = "sec1", "ECDSA"
+	case "PRIVATE KEY":
+		info.Format = "pkcs8"
+	case "ENCRYPTED PRIVATE KEY":
+		info.Format, info.Encrypted = "pkcs8", true
+		return info, nil
+	}
+
+	// Legacy OpenSSL encryption keeps the PKCS#1 or SEC 1 block type.
+	if block.Headers["Proc-Type"] == "4,ENCRYPTED" {
+		info.Encrypted = true
+		return info, nil
+	}
+
+	var key any
+	var err error
+	switch info.Format {
+	case "pkcs1":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "sec1":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	return info, describeKey(info, key)
+}
+
+// parsePrivateKeyDER recognizes unencrypted DER private keys.
+func parsePrivateKeyDER(fp string, der []byte) (*KeyInfo, error) {
+	info := &KeyInfo{Path: fp}
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		info.Format = "pkcs8"
+		return info, describeKey(info, key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		info.Format = "pkcs1"
+		return info, describeKey(info, key)
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		info.Format = "sec1"
+		return info, describeKey(info, key)
+	}
+	return nil, errors.New("not a private key")
+}
+
+func describeKey(info *KeyInfo, key any) error {
+	signer, ok := key.(interface{ Public() crypto.PublicKey })
+	if !ok {
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	pub := signer.Public()
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		info.Algorithm = "RSA"
+	case *ecdsa.PublicKey:
+		info.Algorithm = "ECDSA"
+	case ed25519.PublicKey:
+		info.Algorithm = "Ed25519"
+	case *ecdh.PublicKey:
+		info.Algorithm = "ECDH"
+	}
+
+	id, err := publicKeyID(pub)
+	if err != nil {
+		return err
+	}
+	info.PublicKeyID = id
+	return nil
+}
+
+// publicKeyID is the hex SHA-256 of the DER SubjectPublicKeyInfo of pub. It
+// is the same for a certificate and its private key.
+func publicKeyID(pub any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// keyIndex collects the keys and leaf certificates of one scan to pair them
+// once every file has been parsed.
+type keyIndex struct {
+	mu       sync.Mutex
+	keys     []*KeyInfo
+	leaves   map[string]*CertificateInfo // first leaf of each file, by path
+	certKeys map[string]bool             // public key IDs of all certificates
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{
+		leaves:   make(map[string]*CertificateInfo),
+		certKeys: make(map[string]bool),
+	}
+}
+
+func (x *keyIndex) add(result ScanResult) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.keys = append(x.keys, result.Keys...)
+	for _, info := range result.CertInfos {
+		// The key of a CSR has no certificate yet
+		if info.PublicKeyID == "" || info.Kind != "" {
+			continue
+		}
+		x.certKeys[info.PublicKeyID] = true
+
+		// Keystore and kubeconfig entries carry their keys inside the
+		// container, which is not tracked here.
+		if info.Alias == "" && !info.IsCA {
+			if _, ok := x.leaves[info.Path]; !ok {
+				x.leaves[info.Path] = info
+			}
+		}
+	}
+}
+
+// findings reports keys paired by name with a certificate they do not
+// match, keys without any certificate and leaf certificates without a key.
+// Certificates are only expected to have a key in directories that hold
+// keys, so bundles and copies kept elsewhere are not reported.
+func (x *keyIndex) findings() []*Finding {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	var findings []*Finding
+	paired := make(map[string]bool)
+	keyIDs := make(map[string]bool)
+	keyDirs := make(map[string]bool)
+
+	for _, key := range x.keys {
+		keyDirs[filepath.Dir(key.Path)] = true
+
+		var partners []*CertificateInfo
+		for _, candidate := range certCandidates(key.Path) {
+			if leaf, ok := x.leaves[candidate]; ok {
+				partners = append(partners, leaf)
+				paired[leaf.Path] = true
+			}
+		}
+
+		if key.PublicKeyID == "" {
+			continue
+		}
+		keyIDs[key.PublicKeyID] = true
+
+		mismatched := false
+		for _, leaf := range partners {
+			if leaf.PublicKeyID != key.PublicKeyID {
+				mismatched = true
+				findings = append(findings, &Finding{
+					Type:     FindingKeyMismatch,
+					Severity: SeverityCritical,
+					Path:     key.Path,
+					Message:  fmt.Sprintf("private key does not match certificate %s", leaf.Path),
+				})
+			}
+		}
+
+		if !mismatched && !x.certKeys[key.PublicKeyID] {
+			findings = append(findings, &Finding{
+				Type:     FindingOrphanKey,
+				Severity: SeverityWarn,
+				Path:     key.Path,
+				Message:  "no certificate found for private key",
+			})
+		}
+	}
+
+	for _, leaf := range x.leaves {
+		if paired[leaf.Path] || keyIDs[leaf.PublicKeyID] || !keyDirs[filepath.Dir(leaf.Path)] {
+			continue
+		}
+		findings = append(findings, &Finding{
+			Type:            FindingMissingKey,
+			Severity:        SeverityInfo,
+			Path:            leaf.Path,
+			Message:         "no private key found for certificate",
+			ExpirationDate:  leaf.ExpirationDate,
+			DaysUntilExpiry: leaf.DaysUntilExpiry,
+			SerialNumber:    leaf.SerialNumber,
+		})
+	}
+
+	slices.SortStableFunc(findings, func(a, b *Finding) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return findings
+}
+
+// certCandidates lists the files expected to hold the certificate of the key
+// at keyPath: the file itself, files with the same name and a certificate
+// extension (tls.key and tls.crt, server-key.pem and server.pem) and
+// certbot's cert.pem and fullchain.pem next to privkey.pem.
+func certCandidates(keyPath string) []string {
+	dir, base := filepath.Split(keyPath)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+
+	candidates := []string{keyPath}
+	if stem == "privkey" {
+		return append(candidates, dir+"cert.pem", dir+"fullchain.pem")
+	}
+
+	stem = strings.TrimSuffix(strings.TrimSuffix(stem, "-key"), "_key")
+	for _, ext := range []string{".crt", ".pem", ".cer"} {
+		if candidate := dir + stem + ext; candidate != keyPath {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
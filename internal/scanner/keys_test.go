@@ -0,0 +1,162 @@
+package scanner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestParsePrivateKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		format    string
+		algorithm string
+		encrypted bool
+	}{
+		{"pkcs1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), "pkcs1", "RSA", false},
+		{"pkcs8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), "pkcs8", "ECDSA", false},
+		{"sec1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), "sec1", "ECDSA", false},
+		{"der pkcs8", pkcs8, "pkcs8", "ECDSA", false},
+		{"encrypted pkcs8", pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte{0x30, 0x00}}), "pkcs8", "", true},
+		{"legacy encrypted", pem.EncodeToMemory(&pem.Block{
+			Type:    "RSA PRIVATE KEY",
+			Headers: map[string]string{"Proc-Type": "4,ENCRYPTED", "DEK-Info": "AES-128-CBC,00000000000000000000000000000000"},
+			Bytes:   []byte{0x00},
+		}), "pkcs1", "RSA", true},
+	}
+
+	p := NewParser(false, 30)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents, err := p.parseData("tls.key", tt.data)
+			if err != nil {
+				t.Fatalf("parseData() failed: %v", err)
+			}
+			if len(contents.certs) != 0 || len(contents.keys) != 1 {
+				t.Fatalf("Expected 1 key and no certificates, got %d keys and %d certificates", len(contents.keys), len(contents.certs))
+			}
+
+			key := contents.keys[0]
+			if key.Format != tt.format || key.Algorithm != tt.algorithm || key.Encrypted != tt.encrypted {
+				t.Errorf("Unexpected key %+v", key)
+			}
+			if !tt.encrypted && key.PublicKeyID == "" {
+				t.Error("Expected a public key ID")
+			}
+		})
+	}
+
+	if _, err := p.ParseData("tls.key", tests[0].data); err == nil {
+		t.Error("Expected ParseData() to fail on a file without certificates")
+	}
+}
+
+func TestKeyPairing(t *testing.T) {
+	expiry := time.Now().AddDate(1, 0, 0)
+	paired := issueTestCert(t, "paired.example.com", false, expiry, nil)
+	renewed := issueTestCert(t, "renewed.example.com", false, expiry, nil)
+	orphan := issueTestCert(t, "orphan.example.com", false, expiry, nil)
+	lonely := issueTestCert(t, "lonely.example.com", false, expiry, nil)
+	elsewhere := issueTestCert(t, "elsewhere.example.com", false, expiry, nil)
+	pending := issueTestCert(t, "pending.example.com", false, expiry, nil)
+
+	dir := t.TempDir()
+	writeKey := func(name string, c *testCA) {
+		der, err := x509.MarshalPKCS8PrivateKey(c.key)
+		if err != nil {
+			t.Fatalf("Failed to marshal key: %v", err)
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+	}
+
+	writeTestPEM(t, filepath.Join(dir, "paired.crt"), paired)
+	writeKey("paired.key", paired)
+	writeTestPEM(t, filepath.Join(dir, "stale.crt"), renewed)
+	writeKey("stale.key", paired)
+	writeKey("orphan.key", orphan)
+	writeTestPEM(t, filepath.Join(dir, "lonely.crt"), lonely)
+
+	// A key with only a CSR has no certificate yet
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pending.cert.Subject}, pending.key)
+	if err != nil {
+		t.Fatalf("Failed to create CSR: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pending.csr"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}), 0644); err != nil {
+		t.Fatalf("Failed to write CSR: %v", err)
+	}
+	writeKey("pending.key", pending)
+
+	bundles := filepath.Join(dir, "bundles")
+	if err := os.Mkdir(bundles, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(bundles, "elsewhere.crt"), elsewhere)
+
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".crt", ".csr", ".key"}})
+	resultCh, err := scanner.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	findings := make(map[string]string)
+	var keys int
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		keys += len(result.Keys)
+		for _, f := range result.Findings {
+			findings[filepath.Base(f.Path)] = f.Type
+		}
+	}
+
+	if keys != 4 {
+		t.Errorf("Expected 4 keys, got %d", keys)
+	}
+
+	want := map[string]string{
+		"stale.key":   FindingKeyMismatch,
+		"orphan.key":  FindingOrphanKey,
+		"pending.key": FindingOrphanKey,
+		"lonely.crt":  FindingMissingKey,
+	}
+	if len(findings) != len(want) {
+		t.Errorf("Expected findings %v, got %v", want, findings)
+	}
+	for path, typ := range want {
+		if findings[path] != typ {
+			t.Errorf("Expected %s finding for %s, got %q", typ, path, findings[path])
+		}
+	}
+}
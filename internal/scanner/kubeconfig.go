@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	kubeCADataKey        = "certificate-authority-data"
+	kubeClientDataKey    = "client-certificate-data"
+	kubeClientKeyDataKey = "client-key-data"
+)
+
+// kubeconfigNames are the kubeconfig files kubeadm writes to /etc/kubernetes.
+// They are scanned whatever the configured extensions are.
+var kubeconfigNames = []string{
+	"admin.conf",
+	"super-admin.conf",
+	"kubelet.conf",
+	"controller-manager.conf",
+	"scheduler.conf",
+	"kubeconfig",
+}
+
+// kubeconfigCert is an embedded certificate field of a kubeconfig, tagged
+// with the cluster or user entry it belongs to, e.g. "user/kubernetes-admin".
+type kubeconfigCert struct {
+	entry string
+	data  string
+}
+
+type kubeconfigJSON struct {
+	Clusters []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			CAData string `json:"certificate-authority-data"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			ClientCertData string `json:"client-certificate-data"`
+			ClientKeyData  string `json:"client-key-data"`
+		} `json:"user"`
+	} `json:"users"`
+}
+
+func isKubeconfigName(f string) bool {
+	for _, name := range kubeconfigNames {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isKubeconfig reports whether data looks like a kubeconfig with embedded
+// certificates.
+func isKubeconfig(data []byte) bool {
+	return bytes.Contains(data, []byte(kubeCADataKey)) || bytes.Contains(data, []byte(kubeClientDataKey))
+}
+
+// parseKubeconfig adds the certificates of the kubeconfig data to contents.
+// A field that cannot be decoded is added to contents.errors and does not
+// hide the certificates of the others.
+func (p *Parser) parseKubeconfig(fp string, data []byte, contents *fileContents) error {
+	fields, keys, err := decodeKubeconfig(data)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		pemData, err := base64.StdEncoding.DecodeString(field.data)
+		if err != nil {
+			contents.errors = append(contents.errors, fmt.Errorf("kubeconfig %s: invalid base64: %w", field.entry, err))
+			continue
+		}
+
+		infos, err := p.ParseData(fp, pemData)
+		if err != nil {
+			contents.errors = append(contents.errors, fmt.Errorf("kubeconfig %s: %w", field.entry, err))
+		}
+		for _, info := range infos {
+			info.Alias = field.entry
+		}
+		contents.certs = append(contents.certs, infos...)
+	}
+
+	if len(contents.certs) == 0 {
+		if len(contents.errors) > 0 {
+			return errors.Join(contents.errors...)
+		}
+		return fmt.Errorf("no certificates found in file")
+	}
+	contents.keyContainer = keys > 0
+	return nil
+}
+
+// decodeKubeconfig extracts certificate-authority-data and
+// client-certificate-data from a kubeconfig, and counts the client-key-data
+// fields. JSON documents are decoded fully; YAML is read line by line, which
+// covers what kubectl and kubeadm write without pulling in a YAML library.
+func decodeKubeconfig(data []byte) ([]kubeconfigCert, int, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var kc kubeconfigJSON
+		if err := json.Unmarshal(trimmed, &kc); err != nil {
+			return nil, 0, fmt.Errorf("kubeconfig: %w", err)
+		}
+
+		var fields []kubeconfigCert
+		var keys int
+		for _, c := range kc.Clusters {
+			if c.Cluster.CAData != "" {
+				fields = append(fields, kubeconfigCert{entry: "cluster/" + c.Name, data: c.Cluster.CAData})
+			}
+		}
+		for _, u := range kc.Users {
+			if u.User.ClientCertData != "" {
+				fields = append(fields, kubeconfigCert{entry: "user/" + u.Name, data: u.User.ClientCertData})
+			}
+			if u.User.ClientKeyData != "" {
+				keys++
+			}
+		}
+		return fields, keys, nil
+	}
+
+	var (
+		fields     []kubeconfigCert
+		keys       int
+		kind       string // "cluster" or "user" while inside those lists
+		itemIndent = -1
+		name       string
+		pending    []kubeconfigCert
+	)
+
+	flush := func() {
+		for _, f := range pending {
+			f.entry = kind + "/" + name
+			fields = append(fields, f)
+		}
+		pending, name = nil, ""
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), MaxFileSize)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+
+		if indent == 0 && !strings.HasPrefix(trimmed, "- ") {
+			flush()
+			itemIndent = -1
+			switch yamlKey(trimmed) {
+			case "clusters":
+				kind = "cluster"
+			case "users":
+				kind = "user"
+			default:
+				kind = ""
+			}
+			continue
+		}
+
+		if kind == "" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") && (itemIndent < 0 || indent == itemIndent) {
+			flush()
+			itemIndent = indent
+			trimmed = strings.TrimLeft(trimmed[2:], " ")
+			indent += 2
+		}
+
+		key, value := yamlKey(trimmed), yamlValue(trimmed)
+		switch {
+		case key == "name" && indent == itemIndent+2:
+			name = value
+		case key == kubeCADataKey || key == kubeClientDataKey:
+			pending = append(pending, kubeconfigCert{data: value})
+		case key == kubeClientKeyDataKey && kind == "user" && value != "":
+			keys++
+		}
+	}
+	flush()
+
+	if err := sc.Err(); err != nil {
+		return nil, 0, fmt.Errorf("kubeconfig: %w", err)
+	}
+	return fields, keys, nil
+}
+
+func yamlKey(s string) string {
+	key, _, _ := strings.Cut(s, ":")
+	return strings.TrimSpace(key)
+}
+
+func yamlValue(s string) string {
+	_, value, _ := strings.Cut(s, ":")
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
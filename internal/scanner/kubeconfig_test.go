@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKubeconfig(t *testing.T) {
+	ca := base64.StdEncoding.EncodeToString(generateTestCert(t, time.Now().Add(3650*24*time.Hour)))
+	client := base64.StdEncoding.EncodeToString(generateTestCert(t, time.Now().Add(10*24*time.Hour)))
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"kubeadm YAML", fmt.Sprintf(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: https://10.0.0.1:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    user: kubernetes-admin
+  name: kubernetes-admin@kubernetes
+current-context: kubernetes-admin@kubernetes
+kind: Config
+preferences: {}
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: %s
+    client-key-data: c2VjcmV0
+`, ca, client)},
+		{"indented YAML lists", fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+  - name: kubernetes
+    cluster:
+      certificate-authority-data: "%s"
+users:
+  - name: kubernetes-admin
+    user:
+      client-certificate-data: "%s"
+`, ca, client)},
+		{"JSON", fmt.Sprintf(`{"apiVersion":"v1","kind":"Config",
+"clusters":[{"name":"kubernetes","cluster":{"certificate-authority-data":"%s"}}],
+"users":[{"name":"kubernetes-admin","user":{"client-certificate-data":"%s"}}]}`, ca, client)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(false, 30)
+
+			certInfos, err := p.ParseData("/etc/kubernetes/admin.conf", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseData() failed: %v", err)
+			}
+
+			if len(certInfos) != 2 {
+				t.Fatalf("Expected 2 certificates, got %d", len(certInfos))
+			}
+
+			if certInfos[0].Alias != "cluster/kubernetes" || certInfos[0].IsExpiringSoon {
+				t.Errorf("Unexpected CA certificate: alias %q, expiring %v", certInfos[0].Alias, certInfos[0].IsExpiringSoon)
+			}
+
+			if certInfos[1].Alias != "user/kubernetes-admin" || !certInfos[1].IsExpiringSoon {
+				t.Errorf("Unexpected client certificate: alias %q, expiring %v", certInfos[1].Alias, certInfos[1].IsExpiringSoon)
+			}
+		})
+	}
+}
+
+func TestShouldProcessKubeconfig(t *testing.T) {
+	p := NewParser(false, 30)
+
+	if !p.ShouldProcessFile("admin.conf", []string{".pem", ".crt"}) {
+		t.Errorf("Expected admin.conf to be processed regardless of extensions")
+	}
+
+	if p.ShouldProcessFile("nginx.conf", []string{".pem", ".crt"}) {
+		t.Errorf("Expected nginx.conf to be skipped")
+	}
+}
+
+func TestKubeconfigBadField(t *testing.T) {
+	client := base64.StdEncoding.EncodeToString(generateTestCert(t, time.Now().Add(10*24*time.Hour)))
+	data := fmt.Sprintf(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: not-base64!
+  name: kubernetes
+users:
+- name: kubernetes-admin
+  user:
+    client-certificate-data: %s
+`, client)
+
+	certInfos, err := NewParser(false, 30).ParseData("/etc/kubernetes/admin.conf", []byte(data))
+	if err == nil {
+		t.Error("Expected an error for the bad field")
+	}
+	if len(certInfos) != 1 || certInfos[0].Alias != "user/kubernetes-admin" {
+		t.Errorf("Expected the client certificate, got %+v", certInfos)
+	}
+}
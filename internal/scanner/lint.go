@@ -0,0 +1,173 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/dsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lint rules, usable as Finding types.
+const (
+	RuleWeakRSAKey              = "weak-rsa-key"
+	RuleDSAKey                  = "dsa-key"
+	RuleWeakSignature           = "weak-signature"
+	RuleMissingSAN              = "missing-san"
+	RuleExcessiveLifetime       = "excessive-lifetime"
+	RuleMissingBasicConstraints = "missing-basic-constraints"
+)
+
+const (
+	minRSABits       = 2048
+	maxLeafLifetime  = 398 * 24 * time.Hour // CA/Browser Forum limit
+	lintRulesAll     = "all"
+	lintRuleDisabled = "-"
+)
+
+var lintRules = []string{
+	RuleWeakRSAKey,
+	RuleDSAKey,
+	RuleWeakSignature,
+	RuleMissingSAN,
+	RuleExcessiveLifetime,
+	RuleMissingBasicConstraints,
+}
+
+// ParseLintRules resolves rule specs into the set of enabled rules. "all"
+// enables every rule, a rule name enables it and "-name" disables it, in
+// order: "all,-excessive-lifetime" enables everything but one rule.
+func ParseLintRules(specs []string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		name, disable := strings.CutPrefix(spec, lintRuleDisabled)
+
+		if name == lintRulesAll {
+			for _, rule := range lintRules {
+				enabled[rule] = !disable
+			}
+			continue
+		}
+		if !isLintRule(name) {
+			return nil, fmt.Errorf("unknown lint rule %q", spec)
+		}
+		enabled[name] = !disable
+	}
+
+	for rule, on := range enabled {
+		if !on {
+			delete(enabled, rule)
+		}
+	}
+	return enabled, nil
+}
+
+func isLintRule(name string) bool {
+	for _, rule := range lintRules {
+		if name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLintRules enables the lint stage with the given rules, as returned by
+// ParseLintRules.
+func (p *Parser) SetLintRules(rules map[string]bool) {
+	p.lintRules = rules
+}
+
+func (p *Parser) lintAll(certInfos []*CertificateInfo) {
+	for _, info := range certInfos {
+		if info.cert != nil {
+			info.Findings = append(info.Findings, p.lint(info)...)
+		}
+	}
+}
+
+func (p *Parser) lint(info *CertificateInfo) []*Finding {
+	cert := info.cert
+	var findings []*Finding
+
+	add := func(rule string, severity Severity, format string, args ...any) {
+		if !p.lintRules[rule] {
+			return
+		}
+		findings = append(findings, &Finding{
+			Type:            rule,
+			Severity:        severity,
+			Path:            info.Path,
+			Alias:           info.Alias,
+			Message:         fmt.Sprintf(format, args...),
+			ExpirationDate:  info.ExpirationDate,
+			DaysUntilExpiry: info.DaysUntilExpiry,
+			SerialNumber:    info.SerialNumber,
+		})
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if bits := key.N.BitLen(); bits < minRSABits {
+			add(RuleWeakRSAKey, SeverityCritical, "RSA key of %d bits, at least %d required", bits, minRSABits)
+		}
+	case *dsa.PublicKey:
+		add(RuleDSAKey, SeverityCritical, "DSA key")
+	}
+
+	// The signature of a self-signed root is never checked, so its
+	// algorithm does not matter.
+	if !isSelfSigned(cert) {
+		switch cert.SignatureAlgorithm {
+		case x509.MD2WithRSA, x509.MD5WithRSA:
+			add(RuleWeakSignature, SeverityCritical, "%s signature", cert.SignatureAlgorithm)
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			add(RuleWeakSignature, SeverityWarn, "%s signature", cert.SignatureAlgorithm)
+		}
+	}
+
+	if usedAsCA(cert) {
+		if !cert.BasicConstraintsValid || !cert.IsCA {
+			add(RuleMissingBasicConstraints, SeverityWarn, "CA certificate without the basic constraints CA flag")
+		}
+		return findings
+	}
+
+	if isServerCert(cert) && !hasSAN(cert) {
+		add(RuleMissingSAN, SeverityWarn, "leaf certificate without subject alternative names")
+	}
+
+	if lifetime := cert.NotAfter.Sub(cert.NotBefore); lifetime > maxLeafLifetime {
+		add(RuleExcessiveLifetime, SeverityWarn, "validity of %d days exceeds %d", int(lifetime.Hours()/24), int(maxLeafLifetime.Hours()/24))
+	}
+
+	return findings
+}
+
+// usedAsCA reports whether cert acts as a CA: it claims to be one, may sign
+// certificates, or is a self-signed trust anchor.
+func usedAsCA(cert *x509.Certificate) bool {
+	return cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign != 0 ||
+		(bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.Version < 3)
+}
+
+// isServerCert excludes certificates restricted to other purposes, such as
+// client certificates, that do not need SANs.
+func isServerCert(cert *x509.Certificate) bool {
+	if len(cert.ExtKeyUsage) == 0 {
+		return true
+	}
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageServerAuth || usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSAN(cert *x509.Certificate) bool {
+	return len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0 ||
+		len(cert.EmailAddresses) > 0 || len(cert.URIs) > 0
+}
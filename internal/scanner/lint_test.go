@@ -0,0 +1,125 @@
+package scanner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLintRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    int
+		wantErr bool
+	}{
+		{"all", []string{"all"}, len(lintRules), false},
+		{"all but one", []string{"all", "-excessive-lifetime"}, len(lintRules) - 1, false},
+		{"single", []string{"weak-rsa-key"}, 1, false},
+		{"disabled again", []string{"weak-rsa-key", "-weak-rsa-key"}, 0, false},
+		{"unknown", []string{"no-such-rule"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseLintRules(tt.specs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLintRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(rules) != tt.want {
+				t.Errorf("Expected %d enabled rules, got %v", tt.want, rules)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	now := time.Now()
+	ca := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Lint CA"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+
+	leaf := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "lint.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(2, 0, 0),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &weakKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "lint.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...)
+	if err := os.WriteFile(fp, data, 0644); err != nil {
+		t.Fatalf("Failed to write certificates: %v", err)
+	}
+
+	rules, _ := ParseLintRules([]string{"all"})
+	p := NewParser(false, 30)
+	p.SetLintRules(rules)
+
+	certInfos, err := p.ParseFile(fp)
+	if err != nil {
+		t.Fatalf("ParseFile() failed: %v", err)
+	}
+	if len(certInfos) != 2 {
+		t.Fatalf("Expected 2 certificates, got %d", len(certInfos))
+	}
+
+	types := func(findings []*Finding) []string {
+		var ts []string
+		for _, f := range findings {
+			ts = append(ts, f.Type)
+		}
+		slices.Sort(ts)
+		return ts
+	}
+
+	want := []string{RuleExcessiveLifetime, RuleMissingSAN, RuleWeakRSAKey}
+	if got := types(certInfos[0].Findings); !slices.Equal(got, want) {
+		t.Errorf("Leaf findings = %v, want %v", got, want)
+	}
+	if got := types(certInfos[1].Findings); !slices.Equal(got, []string{RuleMissingBasicConstraints}) {
+		t.Errorf("CA findings = %v, want %v", got, []string{RuleMissingBasicConstraints})
+	}
+
+	p.SetLintRules(map[string]bool{RuleMissingSAN: true})
+	certInfos, err = p.ParseFile(fp)
+	if err != nil {
+		t.Fatalf("ParseFile() failed: %v", err)
+	}
+	if got := types(certInfos[0].Findings); !slices.Equal(got, []string{RuleMissingSAN}) {
+		t.Errorf("Expected only %s with a single rule, got %v", RuleMissingSAN, got)
+	}
+}
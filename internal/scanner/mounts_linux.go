@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const mountInfoPath = "/proc/self/mountinfo"
+
+// loadMounts lists the mount points of the process and their filesystem
+// types, such as proc, sysfs, nfs4 or fuse.sshfs.
+func loadMounts() (map[string]string, error) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMountInfo(f)
+}
+
+// parseMountInfo reads the mountinfo format of proc(5):
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+//
+// where the fifth field is the mount point and the first after the "-"
+// separator the filesystem type.
+func parseMountInfo(r io.Reader) (map[string]string, error) {
+	mounts := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		mount, super, ok := strings.Cut(scanner.Text(), " - ")
+		fields, superFields := strings.Fields(mount), strings.Fields(super)
+		if !ok || len(fields) < 5 || len(superFields) < 1 {
+			return nil, fmt.Errorf("invalid mountinfo line %q", scanner.Text())
+		}
+		mounts[unescapeMount(fields[4])] = superFields[0]
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMount decodes the octal escapes of spaces, tabs, newlines and
+// backslashes in mount points, e.g. "\040".
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
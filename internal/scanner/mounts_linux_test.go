@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	mountInfo := `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+24 22 0:22 / /sys rw,nosuid,nodev,noexec,relatime shared:2 - sysfs sysfs rw
+90 22 0:50 / /mnt/nfs\040share rw,relatime shared:40 - nfs4 server:/export rw,vers=4.2
+91 22 0:51 / /home/user/remote rw,nosuid,nodev,relatime shared:41 - fuse.sshfs user@host: rw
+`
+	mounts, err := parseMountInfo(strings.NewReader(mountInfo))
+	if err != nil {
+		t.Fatalf("parseMountInfo() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"/":                 "ext4",
+		"/proc":             "proc",
+		"/sys":              "sysfs",
+		"/mnt/nfs share":    "nfs4",
+		"/home/user/remote": "fuse.sshfs",
+	}
+	for mountPoint, fsType := range want {
+		if mounts[mountPoint] != fsType {
+			t.Errorf("mounts[%q] = %q, want %q", mountPoint, mounts[mountPoint], fsType)
+		}
+	}
+
+	if _, err := parseMountInfo(strings.NewReader("garbage\n")); err == nil {
+		t.Error("Expected an error for an invalid line")
+	}
+}
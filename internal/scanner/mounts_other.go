@@ -0,0 +1,8 @@
+//go:build !linux
+
+package scanner
+
+// loadMounts is Linux only; elsewhere one-filesystem compares devices.
+func loadMounts() (map[string]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,197 @@
+package scanner
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"time"
+)
+
+// Kinds of object found in files, as listed in ScanResult.Objects.
+const (
+	ObjectCertificate         = "certificate"
+	ObjectTrustedCertificate  = "trusted-certificate"
+	ObjectPKCS7               = "pkcs7"
+	ObjectPKCS12              = "pkcs12"
+	ObjectJKS                 = "jks"
+	ObjectKubeconfig          = "kubeconfig"
+	ObjectPrivateKey          = "private-key"
+	ObjectEncryptedPrivateKey = "encrypted-private-key"
+	ObjectOpenSSHPrivateKey   = "openssh-private-key"
+	ObjectPublicKey           = "public-key"
+	ObjectParameters          = "parameters"
+	ObjectCRL                 = "crl"
+	ObjectCSR                 = "csr"
+)
+
+// Kinds of CertificateInfo besides certificates, whose Kind is empty.
+const (
+	KindCRL = "crl"
+	KindCSR = "csr"
+)
+
+// parseBlock classifies a PEM block and parses the ones padecer evaluates:
+// certificates, bundles, private keys, CRLs and CSRs.
+func (p *Parser) parseBlock(fp string, block *pem.Block, contents *fileContents) error {
+	switch {
+	case block.Type == "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		contents.certs = append(contents.certs, p.buildCertificateInfo(fp, cert))
+		contents.objects = append(contents.objects, ObjectCertificate)
+
+	case block.Type == "TRUSTED CERTIFICATE":
+		cert, err := parseTrustedCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse trusted certificate: %w", err)
+		}
+		contents.certs = append(contents.certs, p.buildCertificateInfo(fp, cert))
+		contents.objects = append(contents.objects, ObjectTrustedCertificate)
+
+	case block.Type == "PKCS7" || block.Type == "PKCS #7 SIGNED DATA":
+		bundle, err := decodePKCS7(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate bundle: %w", err)
+		}
+		for _, cert := range bundle {
+			contents.certs = append(contents.certs, p.buildCertificateInfo(fp, cert))
+		}
+		contents.objects = append(contents.objects, ObjectPKCS7)
+
+	case isPrivateKeyBlock(block.Type):
+		key, err := parsePrivateKeyBlock(fp, block)
+		if err != nil {
+			return err
+		}
+		contents.keys = append(contents.keys, key)
+		if key.Encrypted {
+			contents.objects = append(contents.objects, ObjectEncryptedPrivateKey)
+		} else {
+			contents.objects = append(contents.objects, ObjectPrivateKey)
+		}
+
+	case block.Type == "X509 CRL":
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse CRL: %w", err)
+		}
+		contents.certs = append(contents.certs, p.buildCRLInfo(fp, crl))
+		contents.objects = append(contents.objects, ObjectCRL)
+
+	case block.Type == "CERTIFICATE REQUEST" || block.Type == "NEW CERTIFICATE REQUEST":
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate request: %w", err)
+		}
+		contents.certs = append(contents.certs, p.buildRequestInfo(fp, csr))
+		contents.objects = append(contents.objects, ObjectCSR)
+
+	case block.Type == "OPENSSH PRIVATE KEY":
+		contents.objects = append(contents.objects, ObjectOpenSSHPrivateKey)
+	case block.Type == "PUBLIC KEY" || block.Type == "RSA PUBLIC KEY":
+		contents.objects = append(contents.objects, ObjectPublicKey)
+	case block.Type == "DH PARAMETERS" || block.Type == "X9.42 DH PARAMETERS" || block.Type == "EC PARAMETERS":
+		contents.objects = append(contents.objects, ObjectParameters)
+	default:
+		contents.objects = append(contents.objects, "unknown:"+block.Type)
+	}
+	return nil
+}
+
+// parseDER recognizes a DER certificate, private key, CRL or CSR.
+func (p *Parser) parseDER(fp string, data []byte, contents *fileContents) error {
+	cert, certErr := x509.ParseCertificate(data)
+	if certErr == nil {
+		contents.certs = append(contents.certs, p.buildCertificateInfo(fp, cert))
+		contents.objects = append(contents.objects, ObjectCertificate)
+		return nil
+	}
+
+	if key, err := parsePrivateKeyDER(fp, data); err == nil {
+		contents.keys = append(contents.keys, key)
+		contents.objects = append(contents.objects, ObjectPrivateKey)
+		return nil
+	}
+
+	if crl, err := x509.ParseRevocationList(data); err == nil {
+		contents.certs = append(contents.certs, p.buildCRLInfo(fp, crl))
+		contents.objects = append(contents.objects, ObjectCRL)
+		return nil
+	}
+
+	if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		contents.certs = append(contents.certs, p.buildRequestInfo(fp, csr))
+		contents.objects = append(contents.objects, ObjectCSR)
+		return nil
+	}
+
+	return fmt.Errorf("failed to parse certificate: %w", certErr)
+}
+
+// parseTrustedCertificate parses an OpenSSL TRUSTED CERTIFICATE block: a
+// certificate followed by OpenSSL's trust settings, which are ignored.
+func parseTrustedCertificate(der []byte) (*x509.Certificate, error) {
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &raw); err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(raw.FullBytes)
+}
+
+// buildCRLInfo evaluates a CRL like a certificate that expires at its
+// NextUpdate: past it, relying parties reject the CRL.
+func (p *Parser) buildCRLInfo(fp string, crl *x509.RevocationList) *CertificateInfo {
+	info := &CertificateInfo{
+		Path:      fp,
+		Kind:      KindCRL,
+		NotBefore: crl.ThisUpdate,
+		State:     StateValid,
+	}
+	if crl.Number != nil {
+		info.SerialNumber = crl.Number.String()
+	}
+	if p.includeSubject {
+		info.Issuer = crl.Issuer.String()
+	}
+
+	// NextUpdate is optional; without it the CRL never goes stale.
+	if !crl.NextUpdate.IsZero() {
+		now := time.Now()
+		info.ExpirationDate = crl.NextUpdate
+		info.DaysUntilExpiry = int(crl.NextUpdate.Sub(now).Hours() / 24)
+		info.IsExpired = crl.NextUpdate.Before(now)
+		info.IsExpiringSoon = info.DaysUntilExpiry <= p.daysThreshold && info.DaysUntilExpiry >= 0
+		info.State = certState(now, crl.ThisUpdate, crl.NextUpdate, p.daysThreshold)
+	}
+	return info
+}
+
+// buildRequestInfo reports a CSR for the inventory. It has no validity and
+// is never alerted.
+func (p *Parser) buildRequestInfo(fp string, csr *x509.CertificateRequest) *CertificateInfo {
+	info := &CertificateInfo{
+		Path:               fp,
+		Kind:               KindCSR,
+		State:              StateValid,
+		DNSNames:           csr.DNSNames,
+		EmailAddresses:     csr.EmailAddresses,
+		KeyAlgorithm:       csr.PublicKeyAlgorithm.String(),
+		KeySize:            keySize(csr.PublicKey),
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+	}
+	for _, ip := range csr.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, uri := range csr.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+	info.PublicKeyID, _ = publicKeyID(csr.PublicKey)
+
+	if p.includeSubject {
+		info.Subject = csr.Subject.String()
+	}
+	return info
+}
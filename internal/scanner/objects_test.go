@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestParseBlocks(t *testing.T) {
+	now := time.Now()
+	ca := issueTestCert(t, "CRL Issuer", true, now.AddDate(5, 0, 0), nil)
+
+	crl := func(nextUpdate time.Time) []byte {
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(7),
+			ThisUpdate: now.Add(-time.Hour),
+			NextUpdate: nextUpdate,
+		}, ca.cert, ca.key)
+		if err != nil {
+			t.Fatalf("Failed to create CRL: %v", err)
+		}
+		return der
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "request.example.com"},
+		DNSNames: []string{"request.example.com"},
+	}, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create CSR: %v", err)
+	}
+
+	// OpenSSL appends its trust settings after the certificate.
+	trusted := append(append([]byte(nil), ca.cert.Raw...), 0x30, 0x03, 0x0c, 0x01, 'x')
+
+	var data []byte
+	for _, block := range []*pem.Block{
+		{Type: "TRUSTED CERTIFICATE", Bytes: trusted},
+		{Type: "X509 CRL", Bytes: crl(now.AddDate(0, 0, 3))},
+		{Type: "X509 CRL", Bytes: crl(now.Add(-time.Hour))},
+		{Type: "CERTIFICATE REQUEST", Bytes: csrDER},
+		{Type: "DH PARAMETERS", Bytes: []byte{0x30, 0x00}},
+		{Type: "SOMETHING ELSE", Bytes: []byte{0x00}},
+	} {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+
+	p := NewParser(true, 30)
+	contents, err := p.parseData("mixed.pem", data)
+	if err != nil {
+		t.Fatalf("parseData() failed: %v", err)
+	}
+
+	wantObjects := []string{ObjectTrustedCertificate, ObjectCRL, ObjectCRL, ObjectCSR, ObjectParameters, "unknown:SOMETHING ELSE"}
+	if !slices.Equal(contents.objects, wantObjects) {
+		t.Errorf("objects = %v, want %v", contents.objects, wantObjects)
+	}
+
+	if len(contents.certs) != 4 {
+		t.Fatalf("Expected 4 entries, got %d", len(contents.certs))
+	}
+
+	trustedInfo, staleCRL, expiredCRL, csr := contents.certs[0], contents.certs[1], contents.certs[2], contents.certs[3]
+	if trustedInfo.Kind != "" || trustedInfo.Subject != "CN=CRL Issuer" {
+		t.Errorf("Unexpected trusted certificate %+v", trustedInfo)
+	}
+
+	if staleCRL.Kind != KindCRL || staleCRL.State != StateExpiring || staleCRL.SerialNumber != "7" {
+		t.Errorf("Expected an expiring CRL number 7, got %+v", staleCRL)
+	}
+	if expiredCRL.State != StateExpired || !expiredCRL.NeedsAlert() {
+		t.Errorf("Expected an expired CRL, got state %q", expiredCRL.State)
+	}
+
+	if csr.Kind != KindCSR || csr.Subject != "CN=request.example.com" || csr.NeedsAlert() {
+		t.Errorf("Unexpected CSR %+v", csr)
+	}
+	if !slices.Equal(csr.DNSNames, []string{"request.example.com"}) {
+		t.Errorf("CSR DNSNames = %v", csr.DNSNames)
+	}
+}
+
+func TestParseDERCRL(t *testing.T) {
+	ca := issueTestCert(t, "CRL Issuer", true, time.Now().AddDate(5, 0, 0), nil)
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().AddDate(0, 1, 0),
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create CRL: %v", err)
+	}
+
+	certInfos, err := NewParser(false, 7).ParseData("ca.crl", der)
+	if err != nil {
+		t.Fatalf("ParseData() failed: %v", err)
+	}
+	if len(certInfos) != 1 || certInfos[0].Kind != KindCRL || certInfos[0].State != StateValid {
+		t.Errorf("Expected one valid CRL, got %+v", certInfos)
+	}
+}
+
+func TestParseDataBadBlock(t *testing.T) {
+	first := issueTestCert(t, "first.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	last := issueTestCert(t, "last.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: first.cert.Raw})
+	offset := len(data)
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("corrupt")})...)
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: last.cert.Raw})...)
+
+	certInfos, err := NewParser(true, 30).ParseData("bundle.pem", data)
+	if len(certInfos) != 2 || certInfos[0].Subject != "CN=first.example.com" || certInfos[1].Subject != "CN=last.example.com" {
+		t.Errorf("Expected the two valid certificates, got %+v", certInfos)
+	}
+
+	var blockErr *BlockError
+	if !errors.As(err, &blockErr) {
+		t.Fatalf("Expected a *BlockError, got %v", err)
+	}
+	if blockErr.Index != 1 || blockErr.Offset != offset || blockErr.Type != "CERTIFICATE" {
+		t.Errorf("BlockError = %+v, want index 1 at offset %d", blockErr, offset)
+	}
+}
+
+func TestScanBadBlock(t *testing.T) {
+	cert := issueTestCert(t, "good.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("corrupt")})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.cert.Raw})...)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bundle.pem"), data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".pem"}})
+	resultCh, err := scanner.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var results []ScanResult
+	for result := range resultCh {
+		results = append(results, result)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+	if results[0].Error != nil || len(results[0].CertInfos) != 1 || len(results[0].Errors) != 1 {
+		t.Errorf("Expected one certificate and one block error, got %+v", results[0])
+	}
+}
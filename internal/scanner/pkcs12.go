@@ -0,0 +1,563 @@
+package scanner
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"unicode/utf16"
+)
+
+// ErrNoMatchingPassword is returned for encrypted containers that none of the
+// configured passwords (nor the empty password) could open.
+var ErrNoMatchingPassword = errors.New("encrypted, no matching password")
+
+// maxPKCS12Iterations bounds the iteration counts of the key derivations,
+// which are read from the file: each candidate password pays for them, and
+// the derivation cannot be interrupted by the file timeout. OpenSSL uses
+// 2048, and the slowest writers a few hundred thousand.
+const maxPKCS12Iterations = 10_000_000
+
+var (
+	errTooManyIterations = errors.New("iteration count too high")
+	errUnsupportedMac    = errors.New("unsupported MAC algorithm")
+)
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCertTypeX509    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidSHA1            = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidHMACWithSHA1    = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidPBES2           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidAES128CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidPBEWithSHA3DES  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHA2DES  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+	oidPBEWithSHARC2   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHARC240 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// pkcs12Cert is a certificate found in a PKCS#12 bag together with its
+// friendlyName attribute, if any.
+type pkcs12Cert struct {
+	cert *x509.Certificate
+	name string
+}
+
+// pkcs12Contents is what decodePKCS12 read from a PFX file.
+type pkcs12Contents struct {
+	certs []pkcs12Cert
+	keys  int // key bags
+
+	// locked is set when the file was opened with one of the passwords,
+	// or not fully opened, so that what was read depends on them.
+	locked bool
+}
+
+func (p *Parser) parsePKCS12(fp string, data []byte, contents *fileContents) error {
+	pfx, err := decodePKCS12(data, p.passwords)
+	if err != nil {
+		return err
+	}
+	if len(pfx.certs) == 0 {
+		return fmt.Errorf("no certificates found in file")
+	}
+
+	for _, bag := range pfx.certs {
+		info := p.buildCertificateInfo(fp, bag.cert)
+		info.Alias = bag.name
+		contents.certs = append(contents.certs, info)
+	}
+	contents.keyContainer = pfx.keys > 0
+	contents.locked = pfx.locked
+	return nil
+}
+
+// isPKCS12 reports whether data is a DER-encoded PFX structure.
+func isPKCS12(data []byte) bool {
+	var pfx pfxPdu
+	rest, err := asn1.Unmarshal(data, &pfx)
+	return err == nil && len(rest) == 0 && pfx.Version == 3 && pfx.AuthSafe.ContentType.Equal(oidDataContentType)
+}
+
+// decodePKCS12 returns every certificate bag in a PFX file, and the number
+// of key bags seen. The empty password is tried first, then each of
+// passwords in order.
+func decodePKCS12(data []byte, passwords []string) (*pkcs12Contents, error) {
+	var pfx pfxPdu
+	if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+		return nil, fmt.Errorf("pkcs12: %w", err)
+	}
+
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, fmt.Errorf("pkcs12: only password-protected files are supported")
+	}
+
+	var authSafeData []byte
+	if _, err := asn1.Unmarshal(pfx.AuthSafe.Content.Bytes, &authSafeData); err != nil {
+		return nil, fmt.Errorf("pkcs12: %w", err)
+	}
+
+	var authSafe []contentInfo
+	if _, err := asn1.Unmarshal(authSafeData, &authSafe); err != nil {
+		return nil, fmt.Errorf("pkcs12: %w", err)
+	}
+
+	// A MAC that cannot be checked would otherwise reject every password
+	var macHash func() hash.Hash
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		h, err := pkcs12MacHash(&pfx.MacData)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs12: %w", err)
+		}
+		macHash = h
+	}
+
+	candidates := append([]string{""}, passwords...)
+	for _, password := range candidates {
+		if macHash != nil && !verifyPKCS12Mac(macHash, &pfx.MacData, authSafeData, password) {
+			continue
+		}
+
+		certs, keys, err := decodeAuthSafe(authSafe, password, true)
+		if errors.Is(err, errTooManyIterations) {
+			return nil, fmt.Errorf("pkcs12: %w", err)
+		}
+		if err != nil {
+			continue
+		}
+		return &pkcs12Contents{certs: certs, keys: keys, locked: password != ""}, nil
+	}
+
+	// Certificates are sometimes stored unencrypted next to a protected
+	// key; those are readable without knowing the password.
+	if certs, keys, err := decodeAuthSafe(authSafe, "", false); err == nil && len(certs) > 0 {
+		return &pkcs12Contents{certs: certs, keys: keys, locked: true}, nil
+	}
+
+	return nil, fmt.Errorf("pkcs12: %w", ErrNoMatchingPassword)
+}
+
+func decodeAuthSafe(authSafe []contentInfo, password string, decrypt bool) ([]pkcs12Cert, int, error) {
+	var certs []pkcs12Cert
+	var keys int
+
+	for _, ci := range authSafe {
+		var bags []byte
+
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &bags); err != nil {
+				return nil, 0, err
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			if !decrypt {
+				continue
+			}
+			var ed encryptedData
+			if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, 0, err
+			}
+			plain, err := pbeDecrypt(ed.EncryptedContentInfo.ContentEncryptionAlgorithm, ed.EncryptedContentInfo.EncryptedContent, password)
+			if err != nil {
+				return nil, 0, err
+			}
+			bags = plain
+		default:
+			continue
+		}
+
+		found, foundKeys, err := decodeSafeContents(bags)
+		if err != nil {
+			return nil, 0, err
+		}
+		certs = append(certs, found...)
+		keys += foundKeys
+	}
+
+	return certs, keys, nil
+}
+
+func decodeSafeContents(data []byte) ([]pkcs12Cert, int, error) {
+	var bags []safeBag
+	if _, err := asn1.Unmarshal(data, &bags); err != nil {
+		return nil, 0, err
+	}
+
+	var certs []pkcs12Cert
+	var keys int
+	for _, bag := range bags {
+		if bag.ID.Equal(oidKeyBag) || bag.ID.Equal(oidShroudedKeyBag) {
+			keys++
+			continue
+		}
+		if !bag.ID.Equal(oidCertBag) {
+			continue
+		}
+
+		var cb certBag
+		if _, err := asn1.Unmarshal(bag.Value.Bytes, &cb); err != nil {
+			return nil, 0, err
+		}
+		if !cb.ID.Equal(oidCertTypeX509) {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(cb.Data)
+		if err != nil {
+			return nil, 0, err
+		}
+		certs = append(certs, pkcs12Cert{cert: cert, name: friendlyName(bag.Attributes)})
+	}
+
+	return certs, keys, nil
+}
+
+func friendlyName(attrs []pkcs12Attribute) string {
+	for _, attr := range attrs {
+		if !attr.ID.Equal(oidFriendlyName) {
+			continue
+		}
+		var raw asn1.RawValue
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &raw); err != nil || len(raw.Bytes)%2 != 0 {
+			return ""
+		}
+		units := make([]uint16, len(raw.Bytes)/2)
+		for i := range units {
+			units[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+		}
+		return string(utf16.Decode(units))
+	}
+	return ""
+}
+
+// pkcs12MacHash returns the digest of the MAC of a PFX file, once its
+// parameters are checked.
+func pkcs12MacHash(md *macData) (func() hash.Hash, error) {
+	if err := checkIterations(md.Iterations); err != nil {
+		return nil, err
+	}
+	switch alg := md.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		return sha1.New, nil
+	case alg.Equal(oidSHA256):
+		return sha256.New, nil
+	case alg.Equal(oidSHA512):
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w %s", errUnsupportedMac, alg)
+	}
+}
+
+func verifyPKCS12Mac(h func() hash.Hash, md *macData, message []byte, password string) bool {
+	// Some writers encode the empty password as zero bytes rather than
+	// as a lone BMPString terminator, so both forms are accepted.
+	encodings := [][]byte{bmpPassword(password)}
+	if password == "" {
+		encodings = append(encodings, nil)
+	}
+
+	for _, pw := range encodings {
+		key := pkcs12KDF(h, md.MacSalt, pw, md.Iterations, 3, h().Size())
+		mac := hmac.New(h, key)
+		mac.Write(message)
+		if hmac.Equal(mac.Sum(nil), md.Mac.Digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func pbeDecrypt(alg pkix.AlgorithmIdentifier, ciphertext []byte, password string) ([]byte, error) {
+	var block cipher.Block
+	var iv []byte
+
+	switch {
+	case alg.Algorithm.Equal(oidPBES2):
+		var err error
+		block, iv, err = pbes2Cipher(alg.Parameters.FullBytes, password)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		var params pbeParams
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+		if err := checkIterations(params.Iterations); err != nil {
+			return nil, err
+		}
+		pw := bmpPassword(password)
+		kdf := func(id byte, size int) []byte {
+			return pkcs12KDF(sha1.New, params.Salt, pw, params.Iterations, id, size)
+		}
+
+		var err error
+		switch {
+		case alg.Algorithm.Equal(oidPBEWithSHA3DES):
+			block, err = des.NewTripleDESCipher(kdf(1, 24))
+			iv = kdf(2, des.BlockSize)
+		case alg.Algorithm.Equal(oidPBEWithSHA2DES):
+			key := kdf(1, 16)
+			block, err = des.NewTripleDESCipher(append(key, key[:8]...))
+			iv = kdf(2, des.BlockSize)
+		case alg.Algorithm.Equal(oidPBEWithSHARC2):
+			block = newRC2Cipher(kdf(1, 16), 128)
+			iv = kdf(2, rc2BlockSize)
+		case alg.Algorithm.Equal(oidPBEWithSHARC240):
+			block = newRC2Cipher(kdf(1, 5), 40)
+			iv = kdf(2, rc2BlockSize)
+		default:
+			return nil, fmt.Errorf("unsupported encryption algorithm %s", alg.Algorithm)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("invalid ciphertext length")
+	}
+
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+
+	return unpad(plain, block.BlockSize())
+}
+
+func pbes2Cipher(params []byte, password string) (cipher.Block, []byte, error) {
+	var p pbes2Params
+	if _, err := asn1.Unmarshal(params, &p); err != nil {
+		return nil, nil, err
+	}
+	if !p.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("unsupported key derivation function %s", p.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(p.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, err
+	}
+	if err := checkIterations(kdf.IterationCount); err != nil {
+		return nil, nil, err
+	}
+
+	var h func() hash.Hash
+	switch prf := kdf.PRF.Algorithm; {
+	case len(prf) == 0, prf.Equal(oidHMACWithSHA1):
+		h = sha1.New
+	case prf.Equal(oidHMACWithSHA256):
+		h = sha256.New
+	case prf.Equal(oidHMACWithSHA512):
+		h = sha512.New
+	default:
+		return nil, nil, fmt.Errorf("unsupported PRF %s", prf)
+	}
+
+	var keyLen int
+	var newCipher func([]byte) (cipher.Block, error)
+	switch alg := p.EncryptionScheme.Algorithm; {
+	case alg.Equal(oidAES128CBC):
+		keyLen, newCipher = 16, aes.NewCipher
+	case alg.Equal(oidAES192CBC):
+		keyLen, newCipher = 24, aes.NewCipher
+	case alg.Equal(oidAES256CBC):
+		keyLen, newCipher = 32, aes.NewCipher
+	case alg.Equal(oidDESEDE3CBC):
+		keyLen, newCipher = 24, des.NewTripleDESCipher
+	default:
+		return nil, nil, fmt.Errorf("unsupported encryption scheme %s", alg)
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(p.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := pbkdf2.Key(h, password, kdf.Salt, kdf.IterationCount, keyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := newCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, nil, fmt.Errorf("invalid IV length")
+	}
+
+	return block, iv, nil
+}
+
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, fmt.Errorf("invalid padding")
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+func checkIterations(n int) error {
+	if n > maxPKCS12Iterations {
+		return fmt.Errorf("%w: %d", errTooManyIterations, n)
+	}
+	return nil
+}
+
+// bmpPassword encodes a password as a NUL-terminated BMPString, as required
+// by the PKCS#12 key derivation function.
+func bmpPassword(password string) []byte {
+	units := utf16.Encode([]rune(password))
+	out := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	return append(out, 0, 0)
+}
+
+// pkcs12KDF is the key derivation function from RFC 7292, Appendix B.2.
+func pkcs12KDF(h func() hash.Hash, salt, password []byte, iterations int, id byte, size int) []byte {
+	v := h().BlockSize()
+
+	d := bytes.Repeat([]byte{id}, v)
+	i := append(fillBlocks(salt, v), fillBlocks(password, v)...)
+
+	one := big.NewInt(1)
+	modulus := new(big.Int).Lsh(one, uint(8*v))
+
+	var out []byte
+	for len(out) < size {
+		hh := h()
+		hh.Write(d)
+		hh.Write(i)
+		a := hh.Sum(nil)
+		for n := 1; n < iterations; n++ {
+			hh.Reset()
+			hh.Write(a)
+			a = hh.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		if len(out) >= size {
+			break
+		}
+
+		b := new(big.Int).SetBytes(fillBlocks(a, v)[:v])
+		b.Add(b, one)
+		for j := 0; j < len(i); j += v {
+			ij := new(big.Int).SetBytes(i[j : j+v])
+			ij.Add(ij, b).Mod(ij, modulus)
+			buf := ij.Bytes()
+			clear(i[j : j+v])
+			copy(i[j+v-len(buf):j+v], buf)
+		}
+	}
+
+	return out[:size]
+}
+
+// fillBlocks repeats pattern until it fills a whole number of v-byte blocks.
+func fillBlocks(pattern []byte, v int) []byte {
+	if len(pattern) == 0 {
+		return nil
+	}
+	n := v * ((len(pattern) + v - 1) / v)
+	return bytes.Repeat(pattern, (n+len(pattern)-1)/len(pattern))[:n]
+}
@@ -0,0 +1,135 @@
+package scanner
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRC2Vectors(t *testing.T) {
+	// Test vectors from RFC 2268, section 5.
+	tests := []struct {
+		key, plain, cipher string
+		bits               int
+	}{
+		{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+		{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+		{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+		{"88", "0000000000000000", "61a8a244adacccf0", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	}
+
+	for _, tt := range tests {
+		key, _ := hex.DecodeString(tt.key)
+		plain, _ := hex.DecodeString(tt.plain)
+		block := newRC2Cipher(key, tt.bits)
+
+		out := make([]byte, rc2BlockSize)
+		block.Encrypt(out, plain)
+		if got := hex.EncodeToString(out); got != tt.cipher {
+			t.Errorf("Encrypt(%s) = %s, want %s", tt.key, got, tt.cipher)
+		}
+
+		block.Decrypt(out, out)
+		if got := hex.EncodeToString(out); got != tt.plain {
+			t.Errorf("Decrypt(%s) = %s, want %s", tt.key, got, tt.plain)
+		}
+	}
+}
+
+func TestPKCS12(t *testing.T) {
+	// Fixtures were produced with OpenSSL 3 from a single self-signed
+	// certificate (CN=padecer-test) and its key.
+	tests := []struct {
+		name      string
+		file      string
+		passwords []string
+		wantErr   error
+	}{
+		{"legacy RC2-40 with password", "legacy.p12", []string{"wrong", "changeit"}, nil},
+		{"3DES with password", "des3.p12", []string{"changeit"}, nil},
+		{"PBES2 AES-256 with password", "modern.p12", []string{"secret"}, nil},
+		{"empty password", "empty.p12", nil, nil},
+		{"unencrypted certificates", "plain.p12", nil, nil},
+		{"no matching password", "modern.p12", []string{"wrong"}, ErrNoMatchingPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			p := NewParser(true, 30)
+			p.SetPasswords(tt.passwords)
+
+			certInfos, err := p.ParseData(tt.file, data)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseData() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseData() failed: %v", err)
+			}
+
+			if len(certInfos) != 1 {
+				t.Fatalf("Expected 1 certificate, got %d", len(certInfos))
+			}
+
+			if certInfos[0].Subject != "CN=padecer-test" {
+				t.Errorf("Expected subject CN=padecer-test, got %s", certInfos[0].Subject)
+			}
+
+			if certInfos[0].Alias != "server" {
+				t.Errorf("Expected alias server, got %q", certInfos[0].Alias)
+			}
+		})
+	}
+}
+
+func TestPKCS12MacParameters(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "modern.p12"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*macData)
+		wantErr error
+	}{
+		// As written by openssl pkcs12 -macalg sha384
+		{"unsupported digest", func(md *macData) { md.Mac.Algorithm.Algorithm = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2} }, errUnsupportedMac},
+		{"too many iterations", func(md *macData) { md.Iterations = 1 << 31 }, errTooManyIterations},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pfx pfxPdu
+			if _, err := asn1.Unmarshal(data, &pfx); err != nil {
+				t.Fatalf("Failed to parse fixture: %v", err)
+			}
+			tt.modify(&pfx.MacData)
+			modified, err := asn1.Marshal(pfx)
+			if err != nil {
+				t.Fatalf("Failed to encode fixture: %v", err)
+			}
+
+			p := NewParser(true, 30)
+			p.SetPasswords([]string{"secret"})
+			_, err = p.ParseData("modern.p12", modified)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseData() error = %v, want %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrNoMatchingPassword) {
+				t.Errorf("Keystore reported as locked: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package scanner
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+)
+
+var oidSignedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// signedData is the PKCS#7 SignedData structure (RFC 2315, section 9.1).
+// Only the certificate set is of interest; the rest is kept raw.
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"tag:0,optional"`
+	CRLs             asn1.RawValue `asn1:"tag:1,optional"`
+	SignerInfos      asn1.RawValue
+}
+
+func (p *Parser) parsePKCS7(fp string, data []byte) ([]*CertificateInfo, error) {
+	bundle, err := decodePKCS7(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate bundle: %w", err)
+	}
+	if len(bundle) == 0 {
+		return nil, fmt.Errorf("no certificates found in file")
+	}
+
+	certs := make([]*CertificateInfo, 0, len(bundle))
+	for _, cert := range bundle {
+		certs = append(certs, p.buildCertificateInfo(fp, cert))
+	}
+	return certs, nil
+}
+
+// isPKCS7 reports whether data is a DER-encoded PKCS#7 SignedData bundle.
+func isPKCS7(data []byte) bool {
+	var ci contentInfo
+	rest, err := asn1.Unmarshal(data, &ci)
+	return err == nil && len(rest) == 0 && ci.ContentType.Equal(oidSignedDataContentType)
+}
+
+// decodePKCS7 returns every certificate of a PKCS#7 SignedData bundle, as
+// found in .p7b/.p7c files.
+func decodePKCS7(data []byte) ([]*x509.Certificate, error) {
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+	if !ci.ContentType.Equal(oidSignedDataContentType) {
+		return nil, fmt.Errorf("pkcs7: unsupported content type %s", ci.ContentType)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7: %w", err)
+	}
+	return certs, nil
+}
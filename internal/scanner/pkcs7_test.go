@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func buildTestPKCS7(t *testing.T, certs ...[]byte) []byte {
+	var set []byte
+	for _, der := range certs {
+		set = append(set, der...)
+	}
+
+	empty, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true})
+	content, _ := asn1.Marshal(contentInfo{ContentType: oidDataContentType})
+
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{FullBytes: empty},
+		ContentInfo:      asn1.RawValue{FullBytes: content},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: set},
+		SignerInfos:      asn1.RawValue{FullBytes: empty},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal SignedData: %v", err)
+	}
+
+	der, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedDataContentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal ContentInfo: %v", err)
+	}
+	return der
+}
+
+func TestPKCS7(t *testing.T) {
+	der := buildTestPKCS7(t,
+		testCertDER(t, time.Now().Add(10*24*time.Hour)),
+		testCertDER(t, time.Now().Add(365*24*time.Hour)),
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"DER", der},
+		{"PEM", pem.EncodeToMemory(&pem.Block{Type: "PKCS7", Bytes: der})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(false, 30)
+
+			certInfos, err := p.ParseData("chain.p7b", tt.data)
+			if err != nil {
+				t.Fatalf("ParseData() failed: %v", err)
+			}
+
+			if len(certInfos) != 2 {
+				t.Fatalf("Expected 2 certificates, got %d", len(certInfos))
+			}
+
+			if !certInfos[0].IsExpiringSoon || certInfos[1].IsExpiringSoon {
+				t.Errorf("Expected only the first certificate to be expiring soon")
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package scanner
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess      = 1
+	ioprioClassBestEffort = 2
+	ioprioClassShift      = 13
+)
+
+// LowerPriority gives the process the lowest CPU priority (nice 19) and the
+// lowest best-effort I/O priority. The idle I/O class is not used: on a host
+// that is never idle, the scan would never finish. Linux keeps both
+// priorities per thread, so they are set on every thread of the process;
+// threads started later inherit them.
+func LowerPriority() error {
+	tasks, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, 19); err != nil {
+			errs = append(errs, os.NewSyscallError("setpriority", err))
+		}
+		prio := ioprioClassBestEffort<<ioprioClassShift | 7
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), uintptr(prio)); errno != 0 {
+			errs = append(errs, os.NewSyscallError("ioprio_set", errno))
+		}
+	}
+	return errors.Join(errs...)
+}
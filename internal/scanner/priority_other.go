@@ -0,0 +1,10 @@
+//go:build !unix
+
+package scanner
+
+import "errors"
+
+// LowerPriority is not supported on this platform.
+func LowerPriority() error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build unix && !linux
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// LowerPriority gives the process the lowest CPU priority (nice 19). There
+// is no portable call for the I/O priority outside Linux.
+func LowerPriority() error {
+	return os.NewSyscallError("setpriority", syscall.Setpriority(syscall.PRIO_PROCESS, 0, 19))
+}
@@ -0,0 +1,189 @@
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"padecer/internal/config"
+)
+
+const EndpointTimeout = 10 * time.Second // Per-endpoint dial and handshake timeout
+
+// Endpoint is a remote TLS service whose served chain is evaluated like a
+// certificate file.
+type Endpoint struct {
+	Address    string // host:port
+	ServerName string // SNI sent in the handshake, the host when empty
+	Protocol   string // ProtocolTLS when empty, or a STARTTLS protocol
+}
+
+// ParseEndpoint parses "[protocol://]host:port", optionally followed by
+// "?sni=name". The protocol defaults to "tls"; "smtp", "imap", "pop3",
+// "ldap" and "postgres" upgrade the connection with STARTTLS first.
+func ParseEndpoint(s string) (Endpoint, error) {
+	raw := strings.TrimSpace(s)
+	if !strings.Contains(raw, "://") {
+		raw = "tls://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("invalid endpoint %q: %w", s, err)
+	}
+	if !isProtocol(u.Scheme) {
+		return Endpoint{}, fmt.Errorf("invalid endpoint %q: unsupported protocol %q", s, u.Scheme)
+	}
+	if u.Port() == "" || u.Hostname() == "" {
+		return Endpoint{}, fmt.Errorf("invalid endpoint %q: host and port are required", s)
+	}
+
+	ep := Endpoint{Address: u.Host, ServerName: u.Query().Get("sni")}
+	if u.Scheme != ProtocolTLS {
+		ep.Protocol = u.Scheme
+	}
+	return ep, nil
+}
+
+func (e Endpoint) String() string {
+	s := e.Address
+	if e.Protocol != "" && e.Protocol != ProtocolTLS {
+		s = e.Protocol + "://" + s
+	}
+	if e.ServerName != "" {
+		s += "?sni=" + e.ServerName
+	}
+	return s
+}
+
+func (e Endpoint) serverName() string {
+	if e.ServerName != "" {
+		return e.ServerName
+	}
+	host, _, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		return e.Address
+	}
+	return host
+}
+
+// ProbeEndpoint performs a TLS handshake with ep and evaluates every
+// certificate of the served chain.
+func (p *Parser) ProbeEndpoint(ctx context.Context, ep Endpoint) ([]*CertificateInfo, error) {
+	chain, err := fetchPeerCertificates(ctx, ep)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificates served")
+	}
+
+	certs := make([]*CertificateInfo, 0, len(chain))
+	for _, cert := range chain {
+		certs = append(certs, p.buildCertificateInfo(ep.String(), cert))
+	}
+
+	if p.verifier != nil {
+		p.verifyChains(certs, chain)
+	}
+	if len(p.lintRules) > 0 {
+		p.lintAll(certs)
+	}
+	return certs, nil
+}
+
+func fetchPeerCertificates(ctx context.Context, ep Endpoint) ([]*x509.Certificate, error) {
+	cfg := &tls.Config{
+		ServerName: ep.serverName(),
+		// The chain is inspected, not trusted: expired or self-signed
+		// certificates are exactly what we need to see.
+		InsecureSkipVerify: true,
+	}
+
+	if ep.Protocol == "" || ep.Protocol == ProtocolTLS {
+		d := &tls.Dialer{Config: cfg}
+		conn, err := d.DialContext(ctx, "tcp", ep.Address)
+		if err != nil {
+			return nil, fmt.Errorf("tls handshake failed: %w", err)
+		}
+		defer conn.Close()
+
+		return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", ep.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := startTLS(conn, ep.Protocol); err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+
+	return tlsConn.ConnectionState().PeerCertificates, nil
+}
+
+func (s *Scanner) probeEndpoints(ctx context.Context, resultCh chan<- ScanResult) {
+	sem := make(chan struct{}, s.limits.Workers)
+	var wg sync.WaitGroup
+
+	for _, ep := range s.endpoints {
+		if s.shutdownMgr.IsShuttingDown() {
+			break
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+
+		wg.Add(1)
+		go func(ep Endpoint) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			s.shutdownMgr.Add(1)
+			result := s.processEndpoint(ctx, ep)
+			s.shutdownMgr.Done()
+
+			select {
+			case resultCh <- result:
+			case <-ctx.Done():
+			}
+		}(ep)
+	}
+
+	wg.Wait()
+}
+
+func (s *Scanner) processEndpoint(parentCtx context.Context, ep Endpoint) ScanResult {
+	ctx, cancel := context.WithTimeout(parentCtx, EndpointTimeout)
+	defer cancel()
+
+	certInfos, err := s.p.ProbeEndpoint(ctx, ep)
+	if err != nil {
+		config.Log.Debug("Failed to probe endpoint", "endpoint", ep.String(), "error", err)
+		return ScanResult{Error: fmt.Errorf("failed to probe %s: %w", ep, err)}
+	}
+
+	return ScanResult{CertInfos: certInfos}
+}
@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func generateTestTLSCert(t *testing.T, notAfter time.Time) tls.Certificate {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: priv}
+}
+
+func newTestTLSServer(t *testing.T, cert tls.Certificate) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Endpoint
+		wantErr bool
+	}{
+		{"host and port", "example.com:443", Endpoint{Address: "example.com:443"}, false},
+		{"with sni", "10.0.0.1:8443?sni=api.example.com", Endpoint{Address: "10.0.0.1:8443", ServerName: "api.example.com"}, false},
+		{"tls scheme", "tls://example.com:443", Endpoint{Address: "example.com:443"}, false},
+		{"ipv6", "[::1]:443", Endpoint{Address: "[::1]:443"}, false},
+		{"missing port", "example.com", Endpoint{}, true},
+		{"unknown scheme", "gopher://example.com:70", Endpoint{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEndpoint(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEndpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEndpoint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParserEndpoint(t *testing.T) {
+	srv := newTestTLSServer(t, generateTestTLSCert(t, time.Now().Add(10*24*time.Hour)))
+	ep := Endpoint{Address: srv.Listener.Addr().String(), ServerName: "localhost"}
+
+	p := NewParser(true, 30)
+	certInfos, err := p.ProbeEndpoint(context.Background(), ep)
+	if err != nil {
+		t.Fatalf("ProbeEndpoint() failed: %v", err)
+	}
+
+	if len(certInfos) != 1 {
+		t.Fatalf("Expected 1 certificate, got %d", len(certInfos))
+	}
+
+	if certInfos[0].Path != ep.String() {
+		t.Errorf("Expected path %s, got %s", ep.String(), certInfos[0].Path)
+	}
+
+	if certInfos[0].State != StateExpiring {
+		t.Errorf("Expected state %q, got %q", StateExpiring, certInfos[0].State)
+	}
+}
+
+func TestScanEndpoints(t *testing.T) {
+	srv := newTestTLSServer(t, generateTestTLSCert(t, time.Now().Add(365*24*time.Hour)))
+
+	p := NewParser(false, 30)
+	shutdownMgr := shutdown.NewManager(30 * time.Second)
+	scanner := New(p, shutdownMgr, Options{
+		Extensions: []string{".pem"},
+		Endpoints: []Endpoint{
+			{Address: srv.Listener.Addr().String()},
+			{Address: "127.0.0.1:1"},
+		},
+	})
+
+	resultCh, err := scanner.Scan(context.Background(), []string{t.TempDir()})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var certs, errs int
+	for result := range resultCh {
+		if result.Error != nil {
+			errs++
+			continue
+		}
+		certs += len(result.CertInfos)
+	}
+
+	if certs != 1 {
+		t.Errorf("Expected 1 certificate from the TLS server, got %d", certs)
+	}
+
+	if errs != 1 {
+		t.Errorf("Expected 1 error for the closed port, got %d", errs)
+	}
+}
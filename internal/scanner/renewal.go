@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"slices"
+	"time"
+)
+
+// Renewals tells, in watch mode, when the certificates alerted on are
+// replaced. Certificates are told apart by their fingerprint rather than by
+// their location, as the certificates of a PEM bundle share one.
+type Renewals struct {
+	// the certificates alerted on, by path and then by identity
+	alerting map[string]map[string]*CertificateInfo
+}
+
+// Renewal is a certificate alerted on, and the certificate that replaced it.
+type Renewal struct {
+	Old, New *CertificateInfo
+}
+
+func NewRenewals() *Renewals {
+	return &Renewals{alerting: make(map[string]map[string]*CertificateInfo)}
+}
+
+// Update records the certificates of the files just scanned, and returns the
+// renewals of the certificates alerted on that are no longer found in their
+// file. A certificate removed without a replacement is forgotten, and not
+// counted as renewed.
+func (r *Renewals) Update(infos []*CertificateInfo) []Renewal {
+	byPath := make(map[string][]*CertificateInfo)
+	for _, info := range infos {
+		byPath[info.Path] = append(byPath[info.Path], info)
+	}
+
+	var renewed []Renewal
+	for path, infos := range byPath {
+		alerting := make(map[string]*CertificateInfo)
+		for _, info := range infos {
+			if info.NeedsAlert() {
+				alerting[identity(info)] = info
+			}
+		}
+
+		used := make(map[*CertificateInfo]bool)
+		for id, old := range r.alerting[path] {
+			if _, ok := alerting[id]; ok {
+				continue
+			}
+			if info := replacement(old, infos, used); info != nil {
+				used[info] = true
+				renewed = append(renewed, Renewal{Old: old, New: info})
+			}
+		}
+
+		if len(alerting) > 0 {
+			r.alerting[path] = alerting
+		} else {
+			delete(r.alerting, path)
+		}
+	}
+	return renewed
+}
+
+// identity tells certificates apart: by fingerprint, or for CRLs, which have
+// none, by number and next update.
+func identity(info *CertificateInfo) string {
+	if info.FingerprintSHA256 != "" {
+		return info.Alias + "/" + info.FingerprintSHA256
+	}
+	return info.Alias + "/" + info.Kind + "/" + info.SerialNumber + "/" + info.ExpirationDate.Format(time.RFC3339)
+}
+
+// replacement returns the certificate of infos that is not due and took the
+// place of old: one under the same alias and of the same kind, preferably
+// with the same subject and names.
+func replacement(old *CertificateInfo, infos []*CertificateInfo, used map[*CertificateInfo]bool) *CertificateInfo {
+	var found *CertificateInfo
+	for _, info := range infos {
+		if info.NeedsAlert() || used[info] || info.Alias != old.Alias || info.Kind != old.Kind {
+			continue
+		}
+		if info.Subject == old.Subject && slices.Equal(info.DNSNames, old.DNSNames) {
+			return info
+		}
+		if found == nil {
+			found = info
+		}
+	}
+	return found
+}
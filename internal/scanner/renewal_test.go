@@ -0,0 +1,38 @@
+package scanner
+
+import "testing"
+
+func TestRenewals(t *testing.T) {
+	cert := func(path, fingerprint string, state CertState) *CertificateInfo {
+		return &CertificateInfo{Path: path, FingerprintSHA256: fingerprint, State: state}
+	}
+	r := NewRenewals()
+
+	// A bundle with an expiring certificate and a valid one
+	if renewed := r.Update([]*CertificateInfo{cert("bundle.pem", "a", StateExpiring), cert("bundle.pem", "b", StateValid)}); len(renewed) != 0 {
+		t.Fatalf("Expected no renewal on the first scan, got %d", len(renewed))
+	}
+
+	// Rescanned as is, the valid certificate does not renew the expiring one
+	if renewed := r.Update([]*CertificateInfo{cert("bundle.pem", "a", StateExpiring), cert("bundle.pem", "b", StateValid)}); len(renewed) != 0 {
+		t.Errorf("Expected no renewal of an unchanged bundle, got %d", len(renewed))
+	}
+
+	// Another file is not a replacement
+	if renewed := r.Update([]*CertificateInfo{cert("other.pem", "c", StateValid)}); len(renewed) != 0 {
+		t.Errorf("Expected no renewal from another file, got %d", len(renewed))
+	}
+
+	renewed := r.Update([]*CertificateInfo{cert("bundle.pem", "c", StateValid), cert("bundle.pem", "b", StateValid)})
+	if len(renewed) != 1 {
+		t.Fatalf("Expected 1 renewal, got %d", len(renewed))
+	}
+	if renewed[0].Old.FingerprintSHA256 != "a" || renewed[0].New.FingerprintSHA256 != "c" {
+		t.Errorf("Expected a to be replaced by c, got %s and %s", renewed[0].Old.FingerprintSHA256, renewed[0].New.FingerprintSHA256)
+	}
+
+	// Renewals are reported once
+	if renewed := r.Update([]*CertificateInfo{cert("bundle.pem", "c", StateValid), cert("bundle.pem", "b", StateValid)}); len(renewed) != 0 {
+		t.Errorf("Expected no further renewal, got %d", len(renewed))
+	}
+}
@@ -0,0 +1,221 @@
+package scanner
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Pattern is a gitignore-style glob. Patterns without a slash match file
+// and directory names at any depth ("*.old"), patterns starting with a
+// slash match absolute paths ("/etc/pki/ca-trust/extracted/**") and other
+// patterns match paths relative to the scanned root ("extracted/*.pem").
+// "**" matches any number of directories, a trailing slash restricts the
+// pattern to directories and a leading "!" negates it.
+type Pattern struct {
+	raw      string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool // matched against the absolute path
+	basename bool // matched against the name only
+}
+
+// ParsePatterns compiles gitignore-style patterns.
+func ParsePatterns(specs []string) ([]Pattern, error) {
+	patterns := make([]Pattern, 0, len(specs))
+	for _, spec := range specs {
+		pattern, err := ParsePattern(spec)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ParsePattern compiles a gitignore-style pattern.
+func ParsePattern(s string) (Pattern, error) {
+	raw := strings.TrimSpace(s)
+	p := Pattern{raw: raw}
+
+	glob := raw
+	glob, p.negate = strings.CutPrefix(glob, "!")
+	glob, p.dirOnly = strings.CutSuffix(glob, "/")
+	glob, p.anchored = strings.CutPrefix(glob, "/")
+	p.basename = !p.anchored && !strings.Contains(glob, "/")
+	if glob == "" {
+		return Pattern{}, fmt.Errorf("invalid pattern %q: empty", s)
+	}
+
+	p.segments = strings.Split(glob, "/")
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return Pattern{}, fmt.Errorf("invalid pattern %q: %w", s, err)
+		}
+	}
+	return p, nil
+}
+
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// match reports whether the pattern matches the file or directory fp, found
+// under the scanned root.
+func (p Pattern) match(root, fp string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	fp = filepath.ToSlash(fp)
+	switch {
+	case p.basename:
+		return matchSegments(p.segments, []string{path.Base(fp)})
+	case p.anchored:
+		return matchSegments(p.segments, strings.Split(strings.TrimPrefix(fp, "/"), "/"))
+	default:
+		rel, err := filepath.Rel(root, filepath.FromSlash(fp))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		return matchSegments(p.segments, strings.Split(filepath.ToSlash(rel), "/"))
+	}
+}
+
+// matchSegments matches path segments, "**" standing for zero or more of
+// them. A trailing "**" thus also matches the directory itself, so that
+// excluding "dir/**" prunes dir.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:])
+}
+
+// matchLast applies patterns in order, the last matching one deciding, as
+// in gitignore: fp is matched if that pattern is not negated, and keeps the
+// matched state it had when no pattern matches.
+func matchLast(matched bool, patterns []Pattern, root, fp string, isDir bool) bool {
+	for _, p := range patterns {
+		if p.match(root, fp, isDir) {
+			matched = !p.negate
+		}
+	}
+	return matched
+}
+
+// Root overrides the scan settings for the files under Path.
+type Root struct {
+	Path       string
+	Days       *int      // alert threshold, the Parser's when nil
+	Extensions []string  // the Scanner's when nil
+	Include    []Pattern // added to the Scanner's, relative to Path
+	Exclude    []Pattern // added to the Scanner's, relative to Path
+}
+
+// rootFor returns the Root with the longest Path containing fp, if any.
+func (s *Scanner) rootFor(fp string) *Root {
+	var best *Root
+	for i := range s.roots {
+		root := &s.roots[i]
+		if !within(root.Path, fp) {
+			continue
+		}
+		if best == nil || len(root.Path) > len(best.Path) {
+			best = root
+		}
+	}
+	return best
+}
+
+// topFor returns the longest of paths containing fp, the one it was found
+// under, or "" if none does.
+func topFor(paths []string, fp string) string {
+	top := ""
+	for _, path := range paths {
+		if within(path, fp) && len(path) > len(top) {
+			top = path
+		}
+	}
+	return top
+}
+
+func within(dir, fp string) bool {
+	dir, fp = filepath.Clean(dir), filepath.Clean(fp)
+	return fp == dir || strings.HasPrefix(fp, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// excluded reports whether the exclude patterns, the Scanner's then the
+// Root's, rule out fp. top is the path given to Scan that fp was found in.
+func (s *Scanner) excluded(root *Root, top, fp string, isDir bool) bool {
+	excluded := matchLast(false, s.exclude, top, fp, isDir)
+	if root != nil {
+		excluded = matchLast(excluded, root.Exclude, root.Path, fp, isDir)
+	}
+	return excluded
+}
+
+// included reports whether the file fp passes the include patterns, if
+// there are any.
+func (s *Scanner) included(root *Root, top, fp string) bool {
+	if len(s.include) == 0 && (root == nil || len(root.Include) == 0) {
+		return true
+	}
+	included := matchLast(false, s.include, top, fp, false)
+	if root != nil {
+		included = matchLast(included, root.Include, root.Path, fp, false)
+	}
+	return included
+}
+
+// selected reports whether the file fp, found under top, is scanned: it has
+// one of the extensions of its Root, or files are sniffed, and it passes the
+// include and exclude patterns.
+func (s *Scanner) selected(root *Root, top, fp string) bool {
+	return (s.sniff || s.p.ShouldProcessFile(filepath.Base(fp), s.extensionsFor(root))) &&
+		!s.excluded(root, top, fp, false) && s.included(root, top, fp)
+}
+
+// extensionsFor returns the file suffixes selected under root.
+func (s *Scanner) extensionsFor(root *Root) []string {
+	if root != nil && root.Extensions != nil {
+		return root.Extensions
+	}
+	return s.ext
+}
+
+// parserFor returns the Parser for the file fp, found under top, with the
+// day threshold of its Root and the file size limit of the Scanner. With
+// chain verification, it only reads the files the Scanner selects under top
+// for intermediates.
+func (s *Scanner) parserFor(top, fp string) *Parser {
+	root := s.rootFor(fp)
+	if (root == nil || root.Days == nil) && s.p.fileSizeLimit() == s.limits.MaxFileSize && s.p.verifier == nil {
+		return s.p
+	}
+	p := *s.p
+	if root != nil && root.Days != nil {
+		p.daysThreshold = *root.Days
+	}
+	p.maxFileSize = s.limits.MaxFileSize
+	if p.verifier != nil {
+		p.selectFile = func(fp string) bool {
+			return s.selected(s.rootFor(fp), top, fp)
+		}
+	}
+	return &p
+}
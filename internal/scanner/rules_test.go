@@ -0,0 +1,118 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.old", "/etc/ssl/certs/server.pem.old", false, true},
+		{"*.old", "/etc/ssl/certs/server.pem", false, false},
+		{"/etc/pki/ca-trust/extracted/**", "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", false, true},
+		{"/etc/pki/ca-trust/extracted/**", "/etc/pki/ca-trust/extracted", true, true},
+		{"/etc/pki/ca-trust/extracted/**", "/etc/pki/ca-trust/source/anchor.pem", false, false},
+		{"extracted/*.pem", "/etc/pki/extracted/a.pem", false, true},
+		{"extracted/*.pem", "/etc/pki/ca-trust/extracted/a.pem", false, false},
+		{"**/extracted/*.pem", "/etc/pki/ca-trust/extracted/a.pem", false, true},
+		{"backup/", "/etc/pki/backup", true, true},
+		{"backup/", "/etc/pki/backup", false, false},
+		{"..data/*", "/etc/pki/..data/ca.crt", false, true},
+	}
+
+	for _, tt := range tests {
+		p, err := ParsePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParsePattern(%q) failed: %v", tt.pattern, err)
+		}
+		if got := p.match("/etc/pki", tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q matching %s = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "!", "[a-"} {
+		if _, err := ParsePattern(invalid); err == nil {
+			t.Errorf("ParsePattern(%q) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestScanRules(t *testing.T) {
+	dir := t.TempDir()
+	cert := issueTestCert(t, "rules.example.com", false, time.Now().AddDate(0, 0, 20), nil)
+
+	for _, name := range []string{
+		"tls.pem",
+		"tls.pem.old",
+		"extracted/bundle.pem",
+		"extracted/keep.pem",
+		"k8s/ca.crt",
+		"k8s/ca.pem",
+		"expired-only/tls.pem",
+	} {
+		fp := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		writeTestPEM(t, fp, cert)
+	}
+
+	exclude, err := ParsePatterns([]string{"*.old", "extracted/**"})
+	if err != nil {
+		t.Fatalf("ParsePatterns() failed: %v", err)
+	}
+	keep, err := ParsePatterns([]string{"!keep.pem"})
+	if err != nil {
+		t.Fatalf("ParsePatterns() failed: %v", err)
+	}
+
+	// Within 30 days, except under k8s where only .crt files are read with a
+	// 14-day threshold, and under expired-only, where 0 days is a threshold
+	// of its own.
+	k8sDays, expiredDays := 14, 0
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{
+		Extensions: []string{".pem", ".old"},
+		Exclude:    exclude,
+		Roots: []Root{
+			{Path: filepath.Join(dir, "k8s"), Days: &k8sDays, Extensions: []string{".crt"}},
+			{Path: filepath.Join(dir, "expired-only"), Days: &expiredDays},
+			{Path: filepath.Join(dir, "extracted"), Exclude: keep},
+		},
+	})
+	resultCh, err := scanner.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	alerts := make(map[string]bool)
+	var paths []string
+	for result := range resultCh {
+		for _, info := range result.CertInfos {
+			rel, _ := filepath.Rel(dir, info.Path)
+			paths = append(paths, filepath.ToSlash(rel))
+			alerts[filepath.ToSlash(rel)] = info.NeedsAlert()
+		}
+	}
+
+	slices.Sort(paths)
+	// The "extracted" directory is pruned by the global pattern before its
+	// own rules apply, so keep.pem stays excluded.
+	want := []string{"expired-only/tls.pem", "k8s/ca.crt", "tls.pem"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Scanned %v, want %v", paths, want)
+	}
+	if !alerts["tls.pem"] || alerts["k8s/ca.crt"] || alerts["expired-only/tls.pem"] {
+		t.Errorf("Expected only tls.pem to be alerted, got %v", alerts)
+	}
+}
@@ -1,12 +1,17 @@
 package scanner
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,46 +20,223 @@ import (
 	"padecer/internal/shutdown"
 )
 
+// Defaults of Limits.
 const (
-	workers     = 10
-	MaxDepth    = 20
-	BuffSize    = 100
-	MaxFileSize = 100 * 1024 * 1024 // 100MB limit
-	CertTimeout = 1 * time.Minute   // Per-certificate timeout
+	DefaultWorkers = 10
+	DefaultWalkers = 8
+	MaxDepth       = 20
+	BuffSize       = 100
+	MaxFileSize    = 100 * 1024 * 1024 // 100MB limit
+	CertTimeout    = 1 * time.Minute   // Per-certificate timeout
+)
+
+// TopLevelOnly is the Limits.MaxDepth of a scan that does not enter the
+// subdirectories of its paths, as a MaxDepth of 0 takes the default.
+const TopLevelOnly = -1
+
+// Limits bound the resources of a scan. Zero fields take the defaults
+// above.
+type Limits struct {
+	Workers     int           // files parsed in parallel
+	MaxDepth    int           // directory levels below each path
+	BufferSize  int           // files and results queued between stages
+	MaxFileSize int64         // larger files are not read
+	FileTimeout time.Duration // for reading and parsing one file
+	ScanBudget  time.Duration // after which discovery stops, unlimited when 0
+}
+
+func (l Limits) withDefaults() Limits {
+	l.Workers = cmp.Or(l.Workers, DefaultWorkers)
+	switch l.MaxDepth {
+	case 0:
+		l.MaxDepth = MaxDepth
+	case TopLevelOnly:
+		l.MaxDepth = 0
+	}
+	l.BufferSize = cmp.Or(l.BufferSize, BuffSize)
+	l.MaxFileSize = cmp.Or(l.MaxFileSize, MaxFileSize)
+	l.FileTimeout = cmp.Or(l.FileTimeout, CertTimeout)
+	return l
+}
+
+// CertState is the lifecycle state of a certificate at scan time.
+type CertState string
+
+const (
+	StateValid       CertState = "valid"
+	StateExpiring    CertState = "expiring"
+	StateExpired     CertState = "expired"
+	StateNotYetValid CertState = "not-yet-valid"
+)
+
+// Severity is the alert level attached to everything padecer reports.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "INFO"
+	SeverityWarn     Severity = "WARN"
+	SeverityCritical Severity = "CRITICAL"
 )
 
 type CertificateInfo struct {
 	Path            string    `json:"path"`
+	Alias           string    `json:"alias,omitempty"`
+	Kind            string    `json:"kind,omitempty"` // KindCRL, KindCSR, empty for certificates
 	Subject         string    `json:"subject,omitempty"`
-	ExpirationDate  time.Time `json:"expires"`
+	NotBefore       time.Time `json:"notBefore,omitzero"`
+	ExpirationDate  time.Time `json:"expires,omitzero"`
 	DaysUntilExpiry int       `json:"daysUntilExpiry"`
+	State           CertState `json:"state"`
 	IsExpired       bool      `json:"isExpired"`
 	IsExpiringSoon  bool      `json:"isExpiringSoon"`
 	SerialNumber    string    `json:"serialNumber,omitempty"`
 	Issuer          string    `json:"issuer,omitempty"`
+
+	DNSNames           []string `json:"dnsNames,omitempty"`
+	IPAddresses        []string `json:"ipAddresses,omitempty"`
+	EmailAddresses     []string `json:"emailAddresses,omitempty"`
+	URIs               []string `json:"uris,omitempty"`
+	KeyAlgorithm       string   `json:"keyAlgorithm,omitempty"`
+	KeySize            int      `json:"keySize,omitempty"`
+	SignatureAlgorithm string   `json:"signatureAlgorithm,omitempty"`
+	KeyUsage           []string `json:"keyUsage,omitempty"`
+	ExtKeyUsage        []string `json:"extKeyUsage,omitempty"`
+	SubjectKeyID       string   `json:"subjectKeyId,omitempty"`
+	AuthorityKeyID     string   `json:"authorityKeyId,omitempty"`
+	IsCA               bool     `json:"isCA"`
+	FingerprintSHA1    string   `json:"fingerprintSHA1,omitempty"`
+	FingerprintSHA256  string   `json:"fingerprintSHA256,omitempty"`
+	PublicKeyID        string   `json:"publicKeyId,omitempty"`
+
+	Chain    *ChainInfo `json:"chain,omitempty"`
+	Findings []*Finding `json:"findings,omitempty"`
+
+	cert *x509.Certificate
+}
+
+// Finding is a problem found while scanning that is not the expiry of a
+// certificate itself, such as a stale certificate served by an endpoint.
+type Finding struct {
+	Type            string    `json:"type"`
+	Severity        Severity  `json:"severity"`
+	Path            string    `json:"path"`
+	Alias           string    `json:"alias,omitempty"`
+	Message         string    `json:"message"`
+	ExpirationDate  time.Time `json:"expires,omitzero"`
+	DaysUntilExpiry int       `json:"daysUntilExpiry,omitempty"`
+	SerialNumber    string    `json:"serialNumber,omitempty"`
+}
+
+// NeedsAlert reports whether the certificate is in a state that must be sent.
+func (c *CertificateInfo) NeedsAlert() bool {
+	return c.State != StateValid
+}
+
+// Severity maps the lifecycle state to an alert level.
+func (c *CertificateInfo) Severity() Severity {
+	switch c.State {
+	case StateExpired:
+		return SeverityCritical
+	case StateExpiring, StateNotYetValid:
+		return SeverityWarn
+	default:
+		return SeverityInfo
+	}
 }
 
 type Parser struct {
 	includeSubject bool
 	daysThreshold  int
+	passwords      []string
+	verifier       *verifier
+	lintRules      map[string]bool
+	cache          *Cache
+	maxFileSize    int64 // MaxFileSize when 0
+
+	// selectFile tells which files of a directory are read for the
+	// intermediates of chain verification, all of them when nil.
+	selectFile func(fp string) bool
 }
 
 type Scanner struct {
-	p           *Parser
-	shutdownMgr *shutdown.Manager
-	ext         []string
+	p             *Parser
+	shutdownMgr   *shutdown.Manager
+	ext           []string
+	endpoints     []Endpoint
+	pairs         []Pair
+	keyOwners     []string
+	sniff         bool
+	include       []Pattern
+	exclude       []Pattern
+	roots         []Root
+	symlinks      SymlinkPolicy
+	oneFilesystem bool
+	walkers       int
+	limits        Limits
+	throttle      Throttle
+}
+
+// Options selects what a Scanner looks at besides the paths given to Scan.
+type Options struct {
+	Extensions    []string      // file suffixes to parse, all files when empty
+	Endpoints     []Endpoint    // remote TLS services to probe
+	Pairs         []Pair        // files checked against what an endpoint serves
+	KeyOwners     []string      // users or uids allowed to own private keys, any when empty
+	Sniff         bool          // select files by content instead of Extensions
+	Include       []Pattern     // only files matching these when not empty
+	Exclude       []Pattern     // files and directories skipped
+	Roots         []Root        // settings overridden under some directories
+	Symlinks      SymlinkPolicy // SymlinkFollow when empty
+	OneFilesystem bool          // do not walk into other mounts
+	Walkers       int           // directories read in parallel, DefaultWalkers when 0
+	Limits        Limits
+	Throttle      Throttle
 }
 
 type ScanResult struct {
+	Path      string   // file the result is about, empty for endpoints
+	Objects   []string // kind of every object in the file, e.g. ObjectCRL
 	CertInfos []*CertificateInfo
-	Error     error
+	Keys      []*KeyInfo
+	Findings  []*Finding
+	Error     error   // the whole file or endpoint failed
+	Errors    []error // items that failed while the rest parsed, e.g. *BlockError
+}
+
+// BlockError is a PEM block that could not be parsed. Index counts the PEM
+// blocks of the file from 0 and Offset is the byte offset of its BEGIN line.
+type BlockError struct {
+	Index  int
+	Offset int
+	Type   string
+	Err    error
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %d (%s) at offset %d: %v", e.Index, e.Type, e.Offset, e.Err)
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
 }
 
-func New(p *Parser, shutdownMgr *shutdown.Manager, ext []string) *Scanner {
+func New(p *Parser, shutdownMgr *shutdown.Manager, opts Options) *Scanner {
 	return &Scanner{
-		p:           p,
-		shutdownMgr: shutdownMgr,
-		ext:         ext,
+		p:             p,
+		shutdownMgr:   shutdownMgr,
+		ext:           opts.Extensions,
+		endpoints:     opts.Endpoints,
+		pairs:         opts.Pairs,
+		keyOwners:     opts.KeyOwners,
+		sniff:         opts.Sniff,
+		include:       opts.Include,
+		exclude:       opts.Exclude,
+		roots:         opts.Roots,
+		symlinks:      opts.Symlinks,
+		oneFilesystem: opts.OneFilesystem,
+		walkers:       cmp.Or(opts.Walkers, DefaultWalkers),
+		limits:        opts.Limits.withDefaults(),
+		throttle:      opts.Throttle,
 	}
 }
 
@@ -65,26 +247,77 @@ func NewParser(includeSubject bool, daysThreshold int) *Parser {
 	}
 }
 
+// SetPasswords sets the passwords tried, after the empty one, on encrypted
+// keystores.
+func (p *Parser) SetPasswords(passwords []string) {
+	p.passwords = passwords
+}
+
 func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan ScanResult, error) {
-	resultCh := make(chan ScanResult, BuffSize)
-	fileCh := make(chan string, BuffSize)
+	resultCh := make(chan ScanResult, s.limits.BufferSize)
+	fileCh := make(chan string, s.limits.BufferSize)
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		defer close(fileCh)
-		s.walkPaths(ctx, paths, fileCh)
+		s.walkPaths(ctx, paths, fileCh, resultCh)
 	}()
 
-	for i := 0; i < workers; i++ {
+	if len(s.endpoints) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.probeEndpoints(ctx, resultCh)
+		}()
+	}
+
+	if len(s.pairs) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.checkPairs(ctx, resultCh)
+		}()
+	}
+
+	// Throttling sits between the walkers and the workers, so that
+	// directories are still read ahead while files wait for their turn.
+	workCh := fileCh
+	if s.throttle != (Throttle{}) {
+		throttledCh := make(chan string, s.limits.BufferSize)
+		workCh = throttledCh
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			s.processFiles(ctx, fileCh, resultCh)
+			defer close(throttledCh)
+			s.throttleFiles(ctx, fileCh, throttledCh)
+		}()
+	}
+
+	// File results go through pairKeys, which needs to see all of them
+	// before reporting keys and certificates that do not belong together.
+	fileResultCh := make(chan ScanResult, s.limits.BufferSize)
+	var filesWg sync.WaitGroup
+	for i := 0; i < s.limits.Workers; i++ {
+		filesWg.Add(1)
+		go func() {
+			defer filesWg.Done()
+			s.processFiles(ctx, paths, workCh, fileResultCh)
 		}()
 	}
 
+	go func() {
+		filesWg.Wait()
+		close(fileResultCh)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.pairKeys(ctx, fileResultCh, resultCh)
+	}()
+
 	go func() {
 		wg.Wait()
 		close(resultCh)
@@ -93,25 +326,42 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan ScanResult,
 	return resultCh, nil
 }
 
-func (s *Scanner) walkPaths(ctx context.Context, paths []string, fileCh chan<- string) {
-	for _, rootPath := range paths {
-		if s.shutdownMgr.IsShuttingDown() {
-			return
-		}
-
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+// walkPaths sends the files to scan to fileCh, and the directories that
+// cannot be scanned to resultCh. The directories are read by a pool of
+// walkers.
+func (s *Scanner) walkPaths(ctx context.Context, paths []string, fileCh chan<- string, resultCh chan<- ScanResult) {
+	w := s.newWalk(paths, fileCh, resultCh)
+	pool := newWalkPool(s.walkers)
+	for i, rootPath := range paths {
+		pool.push(i%s.walkers, dirTask{w: w.forRoot(rootPath), path: rootPath})
+	}
 
-		s.walkPath(ctx, rootPath, fileCh, 0)
+	var wg sync.WaitGroup
+	for i := 0; i < s.walkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(i, func(task dirTask) {
+				s.walkPath(ctx, task, func(sub dirTask) { pool.push(i, sub) })
+			})
+		}()
 	}
+	wg.Wait()
 }
 
-func (s *Scanner) walkPath(ctx context.Context, rootPath string, fileCh chan<- string, depth int) {
-	if depth > MaxDepth {
+// walkPath queues the files of the directory of task, found under
+// task.w.top, that pass the extension or sniffing and include/exclude
+// rules, and hands its subdirectories to walk.
+func (s *Scanner) walkPath(ctx context.Context, task dirTask, walk func(dirTask)) {
+	w, rootPath, depth := task.w, task.path, task.depth
+	if depth > s.limits.MaxDepth {
 		config.Log.Warn("Maximum directory depth exceeded", "path", rootPath, "depth", depth)
+		s.walkError(ctx, w.resultCh, &ScanError{Kind: ErrorDepthExceeded, Op: "walk", Path: rootPath, Err: fmt.Errorf("maximum depth of %d exceeded", s.limits.MaxDepth)})
+		return
+	}
+
+	if w.pastDeadline() {
+		s.walkError(ctx, w.resultCh, &ScanError{Kind: ErrorBudgetExceeded, Op: "walk", Path: rootPath, Err: fmt.Errorf("scan budget of %v exceeded, not walked", s.limits.ScanBudget)})
 		return
 	}
 
@@ -130,17 +380,44 @@ func (s *Scanner) walkPath(ctx context.Context, rootPath string, fileCh chan<- s
 		return
 	}
 
-	entries, err := os.ReadDir(rootPath)
+	// Symlink loops and directories reachable through several paths are
+	// walked once
+	if fi, err := os.Stat(rootPath); err == nil && !w.firstVisit(fi) {
+		config.Log.Debug("Directory already walked", "path", rootPath)
+		return
+	}
+
+	entries, err := readDir(rootPath)
 	if err != nil {
 		config.Log.Warn("Failed to read directory", "path", rootPath, "error", err)
-		return
+		kind := ErrorUnreadableDirectory
+		if errors.Is(err, fs.ErrPermission) {
+			kind = ErrorPermissionDenied
+		}
+		s.walkError(ctx, w.resultCh, &ScanError{Kind: kind, Op: "read directory", Path: rootPath, Err: err})
+		// ReadDir returns the entries read before the error
+		if len(entries) == 0 {
+			return
+		}
 	}
 
-	for _, entry := range entries {
+	// Regular files first, so that a file and the links to it are
+	// reported under its own name
+	slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+		return cmp.Compare(a.Type()&fs.ModeSymlink, b.Type()&fs.ModeSymlink)
+	})
+
+	root := s.rootFor(rootPath)
+	for i, entry := range entries {
 		if s.shutdownMgr.IsShuttingDown() {
 			return
 		}
 
+		if w.pastDeadline() {
+			s.walkError(ctx, w.resultCh, &ScanError{Kind: ErrorBudgetExceeded, Op: "walk", Path: rootPath, Err: fmt.Errorf("scan budget of %v exceeded, %d of %d entries not walked", s.limits.ScanBudget, len(entries)-i, len(entries))})
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			return
@@ -149,11 +426,49 @@ func (s *Scanner) walkPath(ctx context.Context, rootPath string, fileCh chan<- s
 
 		fullPath := filepath.Join(rootPath, entry.Name())
 
-		if entry.IsDir() {
-			s.walkPath(ctx, fullPath, fileCh, depth+1)
-		} else if s.p.ShouldProcessFile(entry.Name(), s.ext) {
+		isDir := entry.IsDir()
+		isLink := entry.Type()&fs.ModeSymlink != 0
+		var fi fs.FileInfo
+		if isLink {
+			if s.symlinks == SymlinkSkip {
+				continue
+			}
+			// Dangling links are queued, to be reported as unreadable
+			if fi, err = os.Stat(fullPath); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+
+		if isDir {
+			// Kubernetes projected volumes keep their files in a
+			// "..2024_01_01_..." directory, also reachable through the
+			// "..data" and per-file symlinks, which are scanned instead,
+			// unless symlinks are skipped.
+			if s.symlinks != SymlinkSkip && isVolumeVersion(rootPath, entry.Name()) {
+				continue
+			}
+			if s.excluded(root, w.top, fullPath, true) || (isLink && w.insideTops(fullPath)) {
+				continue
+			}
+			if s.oneFilesystem {
+				if fi == nil {
+					fi, _ = entry.Info()
+				}
+				if fsType := w.otherFilesystem(fullPath, fi); fsType != "" {
+					config.Log.Debug("Mount point skipped", "path", fullPath, "type", fsType)
+					continue
+				}
+			}
+			walk(dirTask{w: w, path: fullPath, depth: depth + 1})
+		} else if s.selected(root, w.top, fullPath) {
+			// Hardlinks and symlinks to a file already queued are skipped
+			if fi, err := os.Stat(fullPath); err == nil && !w.firstVisit(fi) {
+				config.Log.Debug("File already scanned", "path", fullPath)
+				continue
+			}
+
 			select {
-			case fileCh <- fullPath:
+			case w.fileCh <- fullPath:
 			case <-ctx.Done():
 				return
 			}
@@ -161,7 +476,16 @@ func (s *Scanner) walkPath(ctx context.Context, rootPath string, fileCh chan<- s
 	}
 }
 
-func (s *Scanner) processFiles(ctx context.Context, fileCh <-chan string, resultCh chan<- ScanResult) {
+func (s *Scanner) walkError(ctx context.Context, resultCh chan<- ScanResult, err *ScanError) {
+	select {
+	case resultCh <- ScanResult{Path: err.Path, Error: err}:
+	case <-ctx.Done():
+	}
+}
+
+// processFiles parses the files of fileCh, found under the paths given to
+// Scan, and sends their results to resultCh.
+func (s *Scanner) processFiles(ctx context.Context, paths []string, fileCh <-chan string, resultCh chan<- ScanResult) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -175,8 +499,13 @@ func (s *Scanner) processFiles(ctx context.Context, fileCh <-chan string, result
 				return
 			}
 
+			// Throttled files were sniffed by throttleFiles
+			if s.sniff && s.throttle == (Throttle{}) && !sniffFile(fp) {
+				continue
+			}
+
 			s.shutdownMgr.Add(1)
-			result := s.processFileWithContext(ctx, fp)
+			result := s.processFileWithContext(ctx, topFor(paths, fp), fp)
 			s.shutdownMgr.Done()
 
 			select {
@@ -188,30 +517,88 @@ func (s *Scanner) processFiles(ctx context.Context, fileCh <-chan string, result
 	}
 }
 
-func (s *Scanner) processFileWithContext(parentCtx context.Context, fp string) ScanResult {
-	ctx, cancel := context.WithTimeout(parentCtx, CertTimeout)
+func (s *Scanner) processFileWithContext(parentCtx context.Context, top, fp string) ScanResult {
+	ctx, cancel := context.WithTimeout(withScanContext(parentCtx, parentCtx), s.limits.FileTimeout)
 	defer cancel()
 
-	certInfos, err := s.p.ParseFileWithContext(ctx, fp)
+	contents, err := s.parserFor(top, fp).parseFile(ctx, fp)
 	if err != nil {
-		if err == context.DeadlineExceeded {
-			config.Log.Warn("Certificate parsing timeout", "path", fp, "timeout", CertTimeout)
-			return ScanResult{Error: fmt.Errorf("timeout parsing %s after %v", fp, CertTimeout)}
+		if errors.Is(err, context.DeadlineExceeded) {
+			config.Log.Warn("Certificate parsing timeout", "path", fp, "timeout", s.limits.FileTimeout)
+			return ScanResult{Path: fp, Error: fileError(fp, fmt.Errorf("timeout after %v: %w", s.limits.FileTimeout, err))}
 		}
-		if err == context.Canceled {
+		if errors.Is(err, context.Canceled) {
 			config.Log.Debug("Certificate parsing cancelled", "path", fp)
-			return ScanResult{Error: fmt.Errorf("cancelled parsing %s", fp)}
+			return ScanResult{Path: fp, Error: fileError(fp, fmt.Errorf("cancelled: %w", err))}
 		}
 		config.Log.Debug("Failed to parse certificate", "path", fp, "error", err)
-		return ScanResult{Error: fmt.Errorf("failed to parse %s: %w", fp, err)}
+		return ScanResult{Path: fp, Error: fileError(fp, err)}
 	}
 
-	return ScanResult{CertInfos: certInfos}
+	result := ScanResult{Path: fp, Objects: contents.objects, CertInfos: contents.certs, Keys: contents.keys}
+	for _, err := range contents.errors {
+		config.Log.Debug("Failed to parse block", "path", fp, "error", err)
+		result.Errors = append(result.Errors, fileError(fp, err))
+	}
+	if len(contents.keys) > 0 || contents.keyContainer {
+		result.Findings = s.auditKeyFile(fp)
+	}
+	return result
+}
+
+// pairKeys forwards file results and, once all files are parsed, reports
+// the findings of pairing private keys with certificates.
+func (s *Scanner) pairKeys(ctx context.Context, fileResultCh <-chan ScanResult, resultCh chan<- ScanResult) {
+	index := newKeyIndex()
+	for result := range fileResultCh {
+		index.add(result)
+
+		select {
+		case resultCh <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if findings := index.findings(); len(findings) > 0 {
+		select {
+		case resultCh <- ScanResult{Findings: findings}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// isVolumeVersion reports whether name is the versioned directory of a
+// Kubernetes volume in dir, "..2024_01_01_00_00_00.123456789" next to a
+// "..data" symlink, as written by the kubelet's atomic writer.
+func isVolumeVersion(dir, name string) bool {
+	if !volumeVersionName(name) {
+		return false
+	}
+	fi, err := os.Lstat(filepath.Join(dir, "..data"))
+	return err == nil && fi.Mode()&fs.ModeSymlink != 0
+}
+
+func volumeVersionName(name string) bool {
+	stamp, ok := strings.CutPrefix(name, "..")
+	if !ok {
+		return false
+	}
+	stamp, seq, ok := strings.Cut(stamp, ".")
+	if !ok || seq == "" || strings.Trim(seq, "0123456789") != "" {
+		return false
+	}
+	_, err := time.Parse("2006_01_02_15_04_05", stamp)
+	return err == nil
 }
 
 func (s *Scanner) validatePath(path string) error {
-	if strings.Contains(path, "..") {
-		return fmt.Errorf("path traversal detected")
+	// ".." components only: "..data" and the other directories of
+	// Kubernetes volumes are fine
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == filepath.Separator }) {
+		if part == ".." {
+			return fmt.Errorf("path traversal detected")
+		}
 	}
 
 	cleanPath := filepath.Clean(path)
@@ -227,13 +614,27 @@ func (p *Parser) ParseFile(fp string) ([]*CertificateInfo, error) {
 }
 
 func (p *Parser) ParseFileWithContext(ctx context.Context, fp string) ([]*CertificateInfo, error) {
+	contents, err := p.parseFile(ctx, fp)
+	if err != nil {
+		return nil, err
+	}
+	return contents.certificates()
+}
+
+// parseFile is ParseFileWithContext for the scanner, which also wants the
+// private keys and the kind of every object in the file.
+func (p *Parser) parseFile(ctx context.Context, fp string) (*fileContents, error) {
 	fi, err := os.Stat(fp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	if fi.Size() > MaxFileSize {
-		return nil, fmt.Errorf("file size exceeds maximum allowed size of %d bytes", MaxFileSize)
+	if limit := p.fileSizeLimit(); fi.Size() > limit {
+		return nil, fmt.Errorf("%w: size exceeds maximum allowed size of %d bytes", errFileTooLarge, limit)
+	}
+
+	if contents, ok := p.cached(fp, fi); ok {
+		return contents, nil
 	}
 
 	data, err := p.readFileWithContext(ctx, fp)
@@ -241,45 +642,106 @@ func (p *Parser) ParseFileWithContext(ctx context.Context, fp string) ([]*Certif
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	return p.ParseData(fp, data)
+	contents, err := p.parseData(fp, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.verifier != nil {
+		p.verifyFile(ctx, fp, contents.certs)
+	}
+	if len(p.lintRules) > 0 {
+		p.lintAll(contents.certs)
+	}
+	p.store(fp, fi, contents)
+	return contents, nil
 }
 
+// ParseData returns the certificates of data, along with its CRLs and CSRs,
+// which have their Kind set. When some PEM blocks cannot be parsed, it
+// returns the certificates of the others along with an error joining a
+// *BlockError for each bad block.
 func (p *Parser) ParseData(fp string, data []byte) ([]*CertificateInfo, error) {
-	var certs []*CertificateInfo
+	contents, err := p.parseData(fp, data)
+	if err != nil {
+		return nil, err
+	}
+	return contents.certificates()
+}
+
+// fileContents is everything parseData found in a file. objects lists the
+// kind of each PEM block or DER object, in order; errors holds the blocks
+// that could not be parsed. keyContainer is set for keystores and
+// kubeconfigs holding private keys, which are not listed in keys, and locked
+// for containers whose contents depend on the configured passwords.
+type fileContents struct {
+	certs        []*CertificateInfo
+	keys         []*KeyInfo
+	objects      []string
+	errors       []error
+	keyContainer bool
+	locked       bool
+}
+
+func (c *fileContents) certificates() ([]*CertificateInfo, error) {
+	if len(c.errors) > 0 {
+		return c.certs, errors.Join(c.errors...)
+	}
+	if len(c.certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in file")
+	}
+	return c.certs, nil
+}
+
+func (p *Parser) parseData(fp string, data []byte) (*fileContents, error) {
+	contents := &fileContents{}
 	remaining := data
 
-	// Try PEM format first - process all certificate blocks
-	for len(remaining) > 0 {
+	// Try PEM format first - classify every block, keeping the good ones
+	// when others fail
+	for index := 0; len(remaining) > 0; index++ {
 		block, rest := pem.Decode(remaining)
 		if block == nil {
 			break
 		}
 
-		if block.Type == "CERTIFICATE" {
-			cert, err := x509.ParseCertificate(block.Bytes)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse certificate: %w", err)
-			}
-			certs = append(certs, p.buildCertificateInfo(fp, cert))
+		if err := p.parseBlock(fp, block, contents); err != nil {
+			offset := len(data) - len(remaining) + bytes.Index(remaining, []byte("-----BEGIN "+block.Type))
+			contents.errors = append(contents.errors, &BlockError{Index: index, Offset: offset, Type: block.Type, Err: err})
 		}
-
 		remaining = rest
 	}
 
-	// If no PEM certificates found, try DER format
-	if len(certs) == 0 {
-		cert, err := x509.ParseCertificate(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse certificate: %w", err)
-		}
-		certs = append(certs, p.buildCertificateInfo(fp, cert))
+	if len(contents.objects) > 0 {
+		return contents, nil
 	}
-
-	if len(certs) == 0 {
-		return nil, fmt.Errorf("no certificates found in file")
+	// Nothing usable: the file failed as a whole
+	if len(contents.errors) > 0 {
+		return nil, errors.Join(contents.errors...)
 	}
 
-	return certs, nil
+	// If no PEM blocks found, try DER formats
+	var err error
+	switch {
+	case isPKCS12(data):
+		err = p.parsePKCS12(fp, data, contents)
+		contents.objects = append(contents.objects, ObjectPKCS12)
+	case isJKS(data):
+		err = p.parseJKS(fp, data, contents)
+		contents.objects = append(contents.objects, ObjectJKS)
+	case isPKCS7(data):
+		contents.certs, err = p.parsePKCS7(fp, data)
+		contents.objects = append(contents.objects, ObjectPKCS7)
+	case isKubeconfig(data):
+		err = p.parseKubeconfig(fp, data, contents)
+		contents.objects = append(contents.objects, ObjectKubeconfig)
+	default:
+		err = p.parseDER(fp, data, contents)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return contents, nil
 }
 
 func (p *Parser) buildCertificateInfo(fp string, cert *x509.Certificate) *CertificateInfo {
@@ -288,12 +750,16 @@ func (p *Parser) buildCertificateInfo(fp string, cert *x509.Certificate) *Certif
 
 	info := &CertificateInfo{
 		Path:            fp,
+		NotBefore:       cert.NotBefore,
 		ExpirationDate:  cert.NotAfter,
 		DaysUntilExpiry: days,
 		IsExpired:       cert.NotAfter.Before(now),
 		IsExpiringSoon:  days <= p.daysThreshold && days >= 0,
 		SerialNumber:    cert.SerialNumber.String(),
+		cert:            cert,
 	}
+	describeCertificate(info, cert)
+	info.State = certState(now, cert.NotBefore, cert.NotAfter, p.daysThreshold)
 
 	if p.includeSubject {
 		info.Subject = cert.Subject.String()
@@ -303,8 +769,25 @@ func (p *Parser) buildCertificateInfo(fp string, cert *x509.Certificate) *Certif
 	return info
 }
 
+func (p *Parser) fileSizeLimit() int64 {
+	return cmp.Or(p.maxFileSize, MaxFileSize)
+}
+
+func certState(now, notBefore, notAfter time.Time, daysThreshold int) CertState {
+	switch {
+	case notAfter.Before(now):
+		return StateExpired
+	case now.Before(notBefore):
+		return StateNotYetValid
+	case int(notAfter.Sub(now).Hours()/24) <= daysThreshold:
+		return StateExpiring
+	default:
+		return StateValid
+	}
+}
+
 func (p *Parser) ShouldProcessFile(f string, ext []string) bool {
-	if len(ext) == 0 {
+	if len(ext) == 0 || isKubeconfigName(f) {
 		return true
 	}
 
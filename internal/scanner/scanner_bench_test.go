@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -206,7 +207,7 @@ func BenchmarkConcurrentScanning(b *testing.B) {
 
 			p := NewParser(false, 30)
 			shutdownMgr := shutdown.NewManager(30 * time.Second)
-			scanner := New(p, shutdownMgr, []string{".pem"})
+			scanner := New(p, shutdownMgr, Options{Extensions: []string{".pem"}})
 
 			b.ResetTimer()
 			b.ReportAllocs()
@@ -231,10 +232,75 @@ func BenchmarkConcurrentScanning(b *testing.B) {
 	}
 }
 
+// BenchmarkParallelWalk walks a tree of 1111 directories holding 2 files
+// each, with 1 walker (the former sequential walk) and more. Latency adds
+// an NFS-like round trip to every directory read.
+func BenchmarkParallelWalk(b *testing.B) {
+	tempDir := b.TempDir()
+	certPEM := generateBenchmarkCert(1)
+
+	var mkTree func(dir string, depth int)
+	mkTree = func(dir string, depth int) {
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "cert.pem"), certPEM, 0644)
+		os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a certificate"), 0644)
+		if depth == 3 {
+			return
+		}
+		for i := 0; i < 10; i++ {
+			mkTree(filepath.Join(dir, fmt.Sprintf("d%d", i)), depth+1)
+		}
+	}
+	mkTree(tempDir, 0)
+
+	defer func(orig func(string) ([]os.DirEntry, error)) { readDir = orig }(readDir)
+
+	for _, bench := range []struct {
+		latency time.Duration
+		walkers int
+	}{
+		{0, 1}, {0, 4}, {0, 8}, {0, 16},
+		{500 * time.Microsecond, 1}, {500 * time.Microsecond, 4}, {500 * time.Microsecond, 8}, {500 * time.Microsecond, 16},
+	} {
+		latency, walkers := bench.latency, bench.walkers
+		readDir = func(name string) ([]os.DirEntry, error) {
+			time.Sleep(latency)
+			return os.ReadDir(name)
+		}
+
+		b.Run(fmt.Sprintf("Latency%v/Walkers%d", latency, walkers), func(b *testing.B) {
+			p := NewParser(false, 30)
+			shutdownMgr := shutdown.NewManager(30 * time.Second)
+			scanner := New(p, shutdownMgr, Options{Extensions: []string{".pem"}, Walkers: walkers})
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				fileCh := make(chan string, BuffSize)
+				resultCh := make(chan ScanResult, BuffSize)
+
+				go func() {
+					defer close(fileCh)
+					scanner.walkPaths(context.Background(), []string{tempDir}, fileCh, resultCh)
+				}()
+
+				var files int
+				for range fileCh {
+					files++
+				}
+				if files != 1111 {
+					b.Fatalf("Expected 1111 files, got %d", files)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkPathValidation(b *testing.B) {
 	p := NewParser(false, 30)
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
-	scanner := New(p, shutdownMgr, []string{})
+	scanner := New(p, shutdownMgr, Options{Extensions: []string{}})
 
 	paths := []string{
 		"/etc/ssl/certs",
@@ -266,7 +332,7 @@ func BenchmarkChannelOperations(b *testing.B) {
 
 	p := NewParser(false, 30)
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
-	scanner := New(p, shutdownMgr, []string{".pem"})
+	scanner := New(p, shutdownMgr, Options{Extensions: []string{".pem"}})
 
 	b.ResetTimer()
 	b.ReportAllocs()
@@ -278,12 +344,12 @@ func BenchmarkChannelOperations(b *testing.B) {
 
 		go func() {
 			defer close(fileCh)
-			scanner.walkPaths(ctx, []string{tempDir}, fileCh)
+			scanner.walkPaths(ctx, []string{tempDir}, fileCh, resultCh)
 		}()
 
 		go func() {
 			defer close(resultCh)
-			scanner.processFiles(ctx, fileCh, resultCh)
+			scanner.processFiles(ctx, []string{tempDir}, fileCh, resultCh)
 		}()
 
 		for range resultCh {
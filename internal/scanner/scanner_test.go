@@ -186,6 +186,39 @@ func TestExpiredCertificate(t *testing.T) {
 	if cert.DaysUntilExpiry >= 0 {
 		t.Errorf("Expected negative days for expired cert, got %d", cert.DaysUntilExpiry)
 	}
+
+	if cert.State != StateExpired {
+		t.Errorf("Expected state %q, got %q", StateExpired, cert.State)
+	}
+
+	if !cert.NeedsAlert() || cert.Severity() != SeverityCritical {
+		t.Errorf("Expired certificate should alert as %s, got %s", SeverityCritical, cert.Severity())
+	}
+}
+
+func TestCertState(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		want      CertState
+	}{
+		{"valid", now.Add(-day), now.Add(90 * day), StateValid},
+		{"expiring", now.Add(-day), now.Add(10 * day), StateExpiring},
+		{"expired", now.Add(-90 * day), now.Add(-day), StateExpired},
+		{"not yet valid", now.Add(day), now.Add(90 * day), StateNotYetValid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := certState(now, tt.notBefore, tt.notAfter, 30); got != tt.want {
+				t.Errorf("certState() = %q, want %q", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestInvalidPEM(t *testing.T) {
@@ -246,7 +279,7 @@ func TestScanner(t *testing.T) {
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
 	ext := []string{".pem", ".crt"}
 
-	scanner := New(p, shutdownMgr, ext)
+	scanner := New(p, shutdownMgr, Options{Extensions: ext})
 
 	if scanner.p != p {
 		t.Errorf("Parser not set correctly")
@@ -264,7 +297,7 @@ func TestScanner(t *testing.T) {
 func TestValidatePath(t *testing.T) {
 	p := NewParser(false, 30)
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
-	scanner := New(p, shutdownMgr, []string{})
+	scanner := New(p, shutdownMgr, Options{Extensions: []string{}})
 
 	tests := []struct {
 		name    string
@@ -273,6 +306,7 @@ func TestValidatePath(t *testing.T) {
 	}{
 		{"path traversal", "/etc/ssl/../../../", true},
 		{"another path traversal", "/etc/../passwd", true},
+		{"kubernetes volume directory", "/var/run/secrets/..2026_01_01_00_00_00.123456789", false},
 		{"valid windows path", "C:\\etc\\ssl\\certs", false},
 	}
 
@@ -299,7 +333,7 @@ func TestWithContext(t *testing.T) {
 
 	p := NewParser(false, 30)
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
-	scanner := New(p, shutdownMgr, []string{".pem"})
+	scanner := New(p, shutdownMgr, Options{Extensions: []string{".pem"}})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -337,7 +371,7 @@ func TestEmptyDirectory(t *testing.T) {
 
 	p := NewParser(false, 30)
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
-	scanner := New(p, shutdownMgr, []string{".pem"})
+	scanner := New(p, shutdownMgr, Options{Extensions: []string{".pem"}})
 
 	ctx := context.Background()
 	resultCh, err := scanner.Scan(ctx, []string{tempDir})
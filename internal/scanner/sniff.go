@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// sniffSize is how much of a file is read to recognize its format.
+const sniffSize = 4096
+
+var pemArmor = []byte("-----BEGIN ")
+
+// sniffFile reports whether the regular file at fp looks like something
+// padecer parses. It never opens FIFOs or devices, which could block.
+func sniffFile(fp string) bool {
+	fi, err := os.Stat(fp)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() == 0 {
+		return false
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		// Let the parser report why the file cannot be read
+		return true
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return true
+	}
+	return sniff(head[:n], fi.Size())
+}
+
+// sniff recognizes the start of a file of the given size: PEM armor, a JKS
+// or JCEKS magic number, a kubeconfig with embedded certificates, or a DER
+// SEQUENCE spanning the whole file, as in certificates, keys, CRLs, CSRs,
+// PKCS#7 bundles and PKCS#12 keystores.
+func sniff(head []byte, size int64) bool {
+	return bytes.Contains(head, pemArmor) || isJKS(head) || isKubeconfig(head) || isDERSequence(head, size)
+}
+
+// isDERSequence checks the SEQUENCE header of head against size, and that
+// its first element is a SEQUENCE, INTEGER (PKCS#1 and PKCS#12 versions) or
+// OBJECT IDENTIFIER (PKCS#7 content type).
+func isDERSequence(head []byte, size int64) bool {
+	if len(head) < 2 || head[0] != 0x30 {
+		return false
+	}
+
+	length, offset := int64(head[1]), 2
+	if head[1]&0x80 != 0 {
+		n := int(head[1] & 0x7f)
+		// Indefinite (BER) and implausibly long lengths are rejected
+		if n == 0 || n > 4 || len(head) < 2+n {
+			return false
+		}
+		length = 0
+		for _, b := range head[2 : 2+n] {
+			length = length<<8 | int64(b)
+		}
+		offset += n
+	}
+
+	if int64(offset)+length != size || len(head) <= offset {
+		return false
+	}
+	switch head[offset] {
+	case 0x30, 0x02, 0x06:
+		return true
+	default:
+		return false
+	}
+}
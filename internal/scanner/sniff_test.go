@@ -0,0 +1,92 @@
+package scanner
+
+import (
+	"context"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestSniff(t *testing.T) {
+	cert := issueTestCert(t, "sniff.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	der := cert.cert.Raw
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), true},
+		{"pem after text", append([]byte("subject=CN=x\n"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...), true},
+		{"der", der, true},
+		{"truncated der", der[:len(der)-10], false},
+		{"jks", []byte{0xfe, 0xed, 0xfe, 0xed, 0, 0, 0, 2}, true},
+		{"kubeconfig", []byte("clusters:\n- cluster:\n    certificate-authority-data: LS0t\n"), true},
+		{"text", []byte("#!/bin/sh\necho hello\n"), false},
+		{"binary", []byte{0x7f, 'E', 'L', 'F', 2, 1, 1}, false},
+		{"indefinite length", []byte{0x30, 0x80, 0x02, 0x01, 0x03, 0x00, 0x00}, false},
+	}
+
+	for _, tt := range tests {
+		head := tt.data[:min(len(tt.data), sniffSize)]
+		if got := sniff(head, int64(len(tt.data))); got != tt.want {
+			t.Errorf("sniff(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanSniff(t *testing.T) {
+	cert := issueTestCert(t, "sniff.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	dir := t.TempDir()
+
+	// A Kubernetes projected volume: the files live in a timestamped
+	// directory and are exposed through symlinks.
+	version := filepath.Join(dir, "..2024_01_01_00_00_00.123")
+	if err := os.Mkdir(version, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(version, "ca-bundle"), cert)
+	for _, link := range [][2]string{
+		{filepath.Base(version), "..data"},
+		{"..data/ca-bundle", "ca-bundle"},
+	} {
+		if err := os.Symlink(link[0], filepath.Join(dir, link[1])); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	writeTestPEM(t, filepath.Join(dir, "tls.crt.bak"), cert)
+	if err := os.WriteFile(filepath.Join(dir, "tls.der"), cert.cert.Raw, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.pem"), []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Sniff: true})
+	resultCh, err := scanner.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var paths []string
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		for _, info := range result.CertInfos {
+			paths = append(paths, filepath.Base(info.Path))
+		}
+	}
+
+	slices.Sort(paths)
+	want := []string{"ca-bundle", "tls.crt.bak", "tls.der"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("Scanned %v, want %v", paths, want)
+	}
+}
@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"padecer/internal/config"
+)
+
+const FindingStaleCertificate = "stale-served-certificate"
+
+// Pair ties a certificate file to the local endpoint that is expected to
+// serve it, e.g. /etc/nginx/tls.crt and 127.0.0.1:443.
+type Pair struct {
+	Path     string
+	Endpoint Endpoint
+}
+
+// ParsePair parses "path=endpoint", where endpoint uses the ParseEndpoint
+// syntax.
+func ParsePair(s string) (Pair, error) {
+	path, endpoint, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok || path == "" || endpoint == "" {
+		return Pair{}, fmt.Errorf("invalid pair %q: expected path=host:port", s)
+	}
+
+	ep, err := ParseEndpoint(endpoint)
+	if err != nil {
+		return Pair{}, fmt.Errorf("invalid pair %q: %w", s, err)
+	}
+	return Pair{Path: path, Endpoint: ep}, nil
+}
+
+func (p Pair) String() string {
+	return p.Path + "=" + p.Endpoint.String()
+}
+
+// CheckPair compares the leaf certificate on disk with the one the endpoint
+// serves. It returns a stale-served-certificate finding when they differ,
+// which usually means the certificate was renewed but the serving process
+// was not reloaded, and nil when they match. When some blocks of the file
+// cannot be parsed, the others are still compared and the error is returned
+// along with the result.
+func (p *Parser) CheckPair(ctx context.Context, pair Pair) (*Finding, error) {
+	onDisk, diskErr := p.ParseFileWithContext(ctx, pair.Path)
+	if len(onDisk) == 0 {
+		return nil, diskErr
+	}
+
+	served, err := p.ProbeEndpoint(ctx, pair.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	disk, live := pairLeaf(onDisk), served[0]
+	if disk == nil {
+		if diskErr == nil {
+			diskErr = fmt.Errorf("no certificate in %s", pair.Path)
+		}
+		return nil, diskErr
+	}
+	if disk.FingerprintSHA256 == live.FingerprintSHA256 {
+		return nil, diskErr
+	}
+
+	severity := SeverityWarn
+	if live.State == StateExpired {
+		severity = SeverityCritical
+	}
+
+	return &Finding{
+		Type:     FindingStaleCertificate,
+		Severity: severity,
+		Path:     pair.Path,
+		Message: fmt.Sprintf("%s serves serial %s (expires %s), file has serial %s (expires %s)",
+			pair.Endpoint, live.SerialNumber, live.ExpirationDate.Format("2006-01-02"),
+			disk.SerialNumber, disk.ExpirationDate.Format("2006-01-02")),
+		ExpirationDate:  live.ExpirationDate,
+		DaysUntilExpiry: live.DaysUntilExpiry,
+		SerialNumber:    live.SerialNumber,
+	}, diskErr
+}
+
+// pairLeaf returns the certificate of a file that is expected to be served:
+// the first one that is not a CA, skipping CRLs and CSRs, or else the first
+// certificate, for self-signed CAs served as is.
+func pairLeaf(infos []*CertificateInfo) *CertificateInfo {
+	var first *CertificateInfo
+	for _, info := range infos {
+		if info.Kind != "" {
+			continue
+		}
+		if !info.IsCA {
+			return info
+		}
+		if first == nil {
+			first = info
+		}
+	}
+	return first
+}
+
+func (s *Scanner) checkPairs(ctx context.Context, resultCh chan<- ScanResult) {
+	for _, pair := range s.pairs {
+		if s.shutdownMgr.IsShuttingDown() {
+			return
+		}
+
+		s.shutdownMgr.Add(1)
+		result := s.processPair(ctx, pair)
+		s.shutdownMgr.Done()
+
+		if result.Error == nil && len(result.Findings) == 0 {
+			continue
+		}
+
+		select {
+		case resultCh <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Scanner) processPair(parentCtx context.Context, pair Pair) ScanResult {
+	ctx, cancel := context.WithTimeout(parentCtx, EndpointTimeout)
+	defer cancel()
+
+	finding, err := s.p.CheckPair(ctx, pair)
+	if err != nil {
+		config.Log.Debug("Failed to check pair", "pair", pair.String(), "error", err)
+		err = fmt.Errorf("failed to check %s: %w", pair, err)
+	}
+
+	switch {
+	case finding != nil && err != nil:
+		return ScanResult{Findings: []*Finding{finding}, Errors: []error{err}}
+	case finding != nil:
+		return ScanResult{Findings: []*Finding{finding}}
+	default:
+		return ScanResult{Error: err}
+	}
+}
@@ -0,0 +1,128 @@
+package scanner
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Pair
+		wantErr bool
+	}{
+		{"file and endpoint", "/etc/nginx/tls.crt=127.0.0.1:443", Pair{Path: "/etc/nginx/tls.crt", Endpoint: Endpoint{Address: "127.0.0.1:443"}}, false},
+		{"with sni", "/etc/tls.crt=127.0.0.1:443?sni=example.com", Pair{Path: "/etc/tls.crt", Endpoint: Endpoint{Address: "127.0.0.1:443", ServerName: "example.com"}}, false},
+		{"missing endpoint", "/etc/tls.crt", Pair{}, true},
+		{"invalid endpoint", "/etc/tls.crt=127.0.0.1", Pair{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePair(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePair() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePair() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPair(t *testing.T) {
+	served := generateTestTLSCert(t, time.Now().Add(10*24*time.Hour))
+	renewed := generateTestTLSCert(t, time.Now().Add(90*24*time.Hour))
+	srv := newTestTLSServer(t, served)
+
+	dir := t.TempDir()
+	writeCert := func(name string, der []byte) string {
+		path := filepath.Join(dir, name)
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write certificate: %v", err)
+		}
+		return path
+	}
+
+	p := NewParser(false, 30)
+	ep := Endpoint{Address: srv.Listener.Addr().String()}
+
+	finding, err := p.CheckPair(context.Background(), Pair{Path: writeCert("same.crt", served.Certificate[0]), Endpoint: ep})
+	if err != nil {
+		t.Fatalf("CheckPair() failed: %v", err)
+	}
+	if finding != nil {
+		t.Errorf("Expected no finding for matching certificates, got %+v", finding)
+	}
+
+	// A CRL and the CA come first in the file, the leaf last
+	ca := issueTestCert(t, "Pair CA", true, time.Now().AddDate(5, 0, 0), nil)
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().AddDate(0, 0, 7),
+	}, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create CRL: %v", err)
+	}
+	var bundle []byte
+	for _, block := range []*pem.Block{
+		{Type: "X509 CRL", Bytes: crl},
+		{Type: "CERTIFICATE", Bytes: ca.cert.Raw},
+		{Type: "CERTIFICATE", Bytes: served.Certificate[0]},
+	} {
+		bundle = append(bundle, pem.EncodeToMemory(block)...)
+	}
+	bundlePath := filepath.Join(dir, "bundle.pem")
+	if err := os.WriteFile(bundlePath, bundle, 0644); err != nil {
+		t.Fatalf("Failed to write bundle: %v", err)
+	}
+	finding, err = p.CheckPair(context.Background(), Pair{Path: bundlePath, Endpoint: ep})
+	if err != nil {
+		t.Fatalf("CheckPair() failed: %v", err)
+	}
+	if finding != nil {
+		t.Errorf("Expected no finding for the leaf after a CRL and a CA, got %+v", finding)
+	}
+
+	stale := Pair{Path: writeCert("renewed.crt", renewed.Certificate[0]), Endpoint: ep}
+	finding, err = p.CheckPair(context.Background(), stale)
+	if err != nil {
+		t.Fatalf("CheckPair() failed: %v", err)
+	}
+	if finding == nil {
+		t.Fatal("Expected a finding for a stale served certificate")
+	}
+	if finding.Type != FindingStaleCertificate || finding.Severity != SeverityWarn {
+		t.Errorf("Unexpected finding %+v", finding)
+	}
+	if finding.Path != stale.Path {
+		t.Errorf("Expected path %s, got %s", stale.Path, finding.Path)
+	}
+
+	// A bad block does not hide the leaf after it
+	corrupt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("corrupt")})
+	corrupt = append(corrupt, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: renewed.Certificate[0]})...)
+	corruptPath := filepath.Join(dir, "corrupt.pem")
+	if err := os.WriteFile(corruptPath, corrupt, 0644); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	finding, err = p.CheckPair(context.Background(), Pair{Path: corruptPath, Endpoint: ep})
+	var blockErr *BlockError
+	if !errors.As(err, &blockErr) {
+		t.Errorf("Expected a *BlockError, got %v", err)
+	}
+	if finding == nil || finding.Type != FindingStaleCertificate {
+		t.Errorf("Expected a finding despite the bad block, got %+v", finding)
+	}
+}
@@ -0,0 +1,251 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"padecer/internal/config"
+)
+
+// Protocols accepted as endpoint schemes. Everything but ProtocolTLS starts
+// in clear text and is upgraded with the protocol's own STARTTLS dialogue.
+const (
+	ProtocolTLS      = "tls"
+	ProtocolSMTP     = "smtp"
+	ProtocolIMAP     = "imap"
+	ProtocolPOP3     = "pop3"
+	ProtocolLDAP     = "ldap"
+	ProtocolPostgres = "postgres"
+)
+
+var protocols = []string{ProtocolTLS, ProtocolSMTP, ProtocolIMAP, ProtocolPOP3, ProtocolLDAP, ProtocolPostgres}
+
+// ldapStartTLSRequest is the LDAPv3 StartTLS extended request (RFC 4511,
+// section 4.14.1) with message ID 1.
+var ldapStartTLSRequest = []byte{
+	0x30, 0x1d, // LDAPMessage
+	0x02, 0x01, 0x01, // messageID 1
+	0x77, 0x18, // [APPLICATION 23] ExtendedRequest
+	0x80, 0x16, // [0] requestName
+	'1', '.', '3', '.', '6', '.', '1', '.', '4', '.', '1', '.', '1', '4', '6', '6', '.', '2', '0', '0', '3', '7',
+}
+
+// postgresSSLRequest is the 8-byte SSLRequest packet: length, then the
+// magic request code 80877103.
+var postgresSSLRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+
+func isProtocol(s string) bool {
+	for _, p := range protocols {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// startTLS runs the clear-text part of protocol on conn, leaving it ready
+// for the TLS client hello.
+func startTLS(conn net.Conn, protocol string) error {
+	r := bufio.NewReader(conn)
+
+	switch protocol {
+	case ProtocolSMTP:
+		return smtpStartTLS(conn, r)
+	case ProtocolIMAP:
+		return imapStartTLS(conn, r)
+	case ProtocolPOP3:
+		return pop3StartTLS(conn, r)
+	case ProtocolLDAP:
+		return ldapStartTLS(conn, r)
+	case ProtocolPostgres:
+		return postgresStartTLS(conn, r)
+	default:
+		return fmt.Errorf("unsupported protocol %q", protocol)
+	}
+}
+
+func smtpStartTLS(conn net.Conn, r *bufio.Reader) error {
+	tp := textproto.NewReader(r)
+
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return fmt.Errorf("smtp greeting: %w", err)
+	}
+
+	helo := config.Hostname
+	if helo == "" {
+		helo = "localhost"
+	}
+	if _, err := fmt.Fprintf(conn, "EHLO %s\r\n", helo); err != nil {
+		return err
+	}
+	if _, _, err := tp.ReadResponse(250); err != nil {
+		return fmt.Errorf("smtp EHLO: %w", err)
+	}
+
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return fmt.Errorf("smtp STARTTLS: %w", err)
+	}
+	return nil
+}
+
+func imapStartTLS(conn net.Conn, r *bufio.Reader) error {
+	tp := textproto.NewReader(r)
+
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		return fmt.Errorf("imap greeting: %s", greeting)
+	}
+
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return fmt.Errorf("imap STARTTLS: %w", err)
+		}
+		if strings.HasPrefix(line, "a001 ") {
+			if !strings.HasPrefix(line, "a001 OK") {
+				return fmt.Errorf("imap STARTTLS: %s", line)
+			}
+			return nil
+		}
+	}
+}
+
+func pop3StartTLS(conn net.Conn, r *bufio.Reader) error {
+	tp := textproto.NewReader(r)
+
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("pop3 greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return fmt.Errorf("pop3 greeting: %s", greeting)
+	}
+
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+	reply, err := tp.ReadLine()
+	if err != nil {
+		return fmt.Errorf("pop3 STLS: %w", err)
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return fmt.Errorf("pop3 STLS: %s", reply)
+	}
+	return nil
+}
+
+func ldapStartTLS(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return err
+	}
+
+	// LDAPMessage ::= SEQUENCE { messageID, ExtendedResponse, ... }, in BER:
+	// servers may use long-form lengths, which encoding/asn1 rejects
+	tag, msg, err := readBER(r)
+	if err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	if tag != 0x30 {
+		return fmt.Errorf("ldap StartTLS: unexpected message tag %#x", tag)
+	}
+	mr := bufio.NewReader(bytes.NewReader(msg))
+	if _, _, err := readBER(mr); err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	tag, op, err := readBER(mr)
+	if err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	// [APPLICATION 24] ExtendedResponse
+	if tag != 0x78 {
+		return fmt.Errorf("ldap StartTLS: unexpected response tag %#x", tag)
+	}
+
+	tag, code, err := readBER(bufio.NewReader(bytes.NewReader(op)))
+	if err != nil {
+		return fmt.Errorf("ldap StartTLS: %w", err)
+	}
+	if tag != 0x0a || len(code) == 0 || len(code) > 4 {
+		return fmt.Errorf("ldap StartTLS: unexpected result tag %#x", tag)
+	}
+	result := 0
+	for _, b := range code {
+		result = result<<8 | int(b)
+	}
+	if result != 0 {
+		return fmt.Errorf("ldap StartTLS: result code %d", result)
+	}
+	return nil
+}
+
+// maxBERLength bounds the LDAP messages read from a peer, which are
+// allocated in full: an ExtendedResponse takes a few dozen bytes.
+const maxBERLength = 64 << 10
+
+// readBER reads one BER element with a low tag number and a definite length,
+// short or long form, from r and returns its tag and contents.
+func readBER(r *bufio.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return 0, nil, errors.New("unsupported BER length")
+		}
+		lb := make([]byte, n)
+		if _, err := io.ReadFull(r, lb); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range lb {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxBERLength {
+		return 0, nil, errors.New("BER element too large")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header[0], body, nil
+}
+
+func postgresStartTLS(conn net.Conn, r *bufio.Reader) error {
+	if _, err := conn.Write(postgresSSLRequest); err != nil {
+		return err
+	}
+
+	reply, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("postgres SSLRequest: %w", err)
+	}
+	switch reply {
+	case 'S':
+		return nil
+	case 'N':
+		return errors.New("postgres SSLRequest: server does not support SSL")
+	default:
+		return fmt.Errorf("postgres SSLRequest: unexpected reply %q", reply)
+	}
+}
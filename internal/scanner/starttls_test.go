@@ -0,0 +1,182 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer accepts one connection, runs dialogue in clear text and then
+// serves cert over TLS.
+func fakeServer(t *testing.T, cert tls.Certificate, dialogue func(conn net.Conn, r *bufio.Reader) error) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		if err := dialogue(conn, bufio.NewReader(conn)); err != nil {
+			return
+		}
+		tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}}).Handshake()
+	}()
+
+	return ln.Addr().String()
+}
+
+func expectLine(r *bufio.Reader, prefix string) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, prefix) {
+		return fmt.Errorf("unexpected command %q", line)
+	}
+	return nil
+}
+
+func TestStartTLS(t *testing.T) {
+	cert := generateTestTLSCert(t, time.Now().Add(10*24*time.Hour))
+
+	tests := []struct {
+		protocol string
+		dialogue func(conn net.Conn, r *bufio.Reader) error
+		wantErr  bool
+	}{
+		{ProtocolSMTP, func(conn net.Conn, r *bufio.Reader) error {
+			io.WriteString(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n")
+			if err := expectLine(r, "EHLO "); err != nil {
+				return err
+			}
+			io.WriteString(conn, "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+			if err := expectLine(r, "STARTTLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "220 2.0.0 Ready to start TLS\r\n")
+			return err
+		}, false},
+		{ProtocolIMAP, func(conn net.Conn, r *bufio.Reader) error {
+			io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+			if err := expectLine(r, "a001 STARTTLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "a001 OK Begin TLS negotiation now\r\n")
+			return err
+		}, false},
+		{ProtocolPOP3, func(conn net.Conn, r *bufio.Reader) error {
+			io.WriteString(conn, "+OK POP3 ready\r\n")
+			if err := expectLine(r, "STLS"); err != nil {
+				return err
+			}
+			_, err := io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+			return err
+		}, false},
+		{ProtocolLDAP, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(ldapStartTLSRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			if !bytes.Equal(req, ldapStartTLSRequest) {
+				return fmt.Errorf("unexpected request %x", req)
+			}
+			// LDAPMessage{1, ExtendedResponse{success, "", ""}}
+			_, err := conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+			return err
+		}, false},
+		{ProtocolLDAP, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(ldapStartTLSRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			// The same in BER, with long-form lengths as some servers send
+			_, err := conn.Write([]byte{0x30, 0x84, 0x00, 0x00, 0x00, 0x10, 0x02, 0x01, 0x01, 0x78, 0x84, 0x00, 0x00, 0x00, 0x07, 0x0a, 0x01, 0x00, 0x04, 0x00, 0x04, 0x00})
+			return err
+		}, false},
+		{ProtocolLDAP, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(ldapStartTLSRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			// LDAPMessage{1, ExtendedResponse{protocolError, "", ""}}
+			conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, 0x02, 0x04, 0x00, 0x04, 0x00})
+			return fmt.Errorf("starttls refused")
+		}, true},
+		{ProtocolLDAP, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(ldapStartTLSRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			// A 100MB LDAPMessage, which is not allocated
+			conn.Write([]byte{0x30, 0x84, 0x06, 0x40, 0x00, 0x00})
+			return fmt.Errorf("oversized response")
+		}, true},
+		{ProtocolPostgres, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(postgresSSLRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			_, err := conn.Write([]byte{'S'})
+			return err
+		}, false},
+		{ProtocolPostgres, func(conn net.Conn, r *bufio.Reader) error {
+			req := make([]byte, len(postgresSSLRequest))
+			if _, err := io.ReadFull(r, req); err != nil {
+				return err
+			}
+			conn.Write([]byte{'N'})
+			return fmt.Errorf("ssl refused")
+		}, true},
+	}
+
+	for _, tt := range tests {
+		name := tt.protocol
+		if tt.wantErr {
+			name += " refused"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ep, err := ParseEndpoint(tt.protocol + "://" + fakeServer(t, cert, tt.dialogue))
+			if err != nil {
+				t.Fatalf("ParseEndpoint() failed: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), EndpointTimeout)
+			defer cancel()
+
+			p := NewParser(false, 30)
+			certInfos, err := p.ProbeEndpoint(ctx, ep)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProbeEndpoint() failed: %v", err)
+			}
+
+			if len(certInfos) != 1 || certInfos[0].State != StateExpiring {
+				t.Fatalf("Expected 1 expiring certificate, got %+v", certInfos)
+			}
+
+			if !strings.HasPrefix(certInfos[0].Path, tt.protocol+"://") {
+				t.Errorf("Expected path to start with %s://, got %s", tt.protocol, certInfos[0].Path)
+			}
+		})
+	}
+}
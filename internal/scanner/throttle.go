@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Throttle caps the rate at which files found by the walkers are handed to
+// the parse workers. Zero fields mean no cap.
+type Throttle struct {
+	FilesPerSecond float64
+	BytesPerSecond int64
+}
+
+// bucket is a token bucket holding up to one second of its rate. A request
+// larger than the bucket, like a file larger than the byte rate, borrows
+// from the following seconds and delays the next requests instead.
+type bucket struct {
+	rate float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait takes n tokens, sleeping until they are available or ctx is done.
+// A nil bucket never waits.
+func (b *bucket) wait(ctx context.Context, n float64) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttleFiles forwards the files of in to out, no faster than the file and
+// byte rates of the Scanner. Files over the size limit are not read, so they
+// only count as files. When files are sniffed, they are sniffed here rather
+// than by the workers, so that the files rejected are only charged the bytes
+// read to recognize them.
+func (s *Scanner) throttleFiles(ctx context.Context, in <-chan string, out chan<- string) {
+	files := newBucket(s.throttle.FilesPerSecond)
+	bytes := newBucket(float64(s.throttle.BytesPerSecond))
+	for fp := range in {
+		if s.shutdownMgr.IsShuttingDown() {
+			return
+		}
+		if err := files.wait(ctx, 1); err != nil {
+			return
+		}
+
+		var size int64
+		if bytes != nil {
+			if fi, err := os.Stat(fp); err == nil && fi.Size() <= s.limits.MaxFileSize {
+				size = fi.Size()
+			}
+		}
+		if s.sniff {
+			head := min(size, sniffSize)
+			if err := bytes.wait(ctx, float64(head)); err != nil {
+				return
+			}
+			if !sniffFile(fp) {
+				continue
+			}
+			size -= head // read again, but from the page cache
+		}
+		if err := bytes.wait(ctx, float64(size)); err != nil {
+			return
+		}
+
+		select {
+		case out <- fp:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
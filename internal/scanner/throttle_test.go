@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestBucket(t *testing.T) {
+	ctx := context.Background()
+	b := newBucket(50)
+
+	start := time.Now()
+	if err := b.wait(ctx, 50); err != nil {
+		t.Fatalf("wait() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("Full bucket waited %v", elapsed)
+	}
+
+	// 10 more tokens take 200ms at 50 per second
+	if err := b.wait(ctx, 10); err != nil {
+		t.Fatalf("wait() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Empty bucket waited only %v", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := b.wait(cancelled, 100); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if err := newBucket(0).wait(ctx, 1e9); err != nil {
+		t.Errorf("Unlimited bucket failed: %v", err)
+	}
+}
+
+func TestScanThrottle(t *testing.T) {
+	dir := t.TempDir()
+	cert := issueTestCert(t, "throttle.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	for i := range 4 {
+		writeTestPEM(t, filepath.Join(dir, fmt.Sprintf("%d.pem", i)), cert)
+	}
+
+	// Two files pass at once, the next two in the following second
+	throttle := Throttle{FilesPerSecond: 2, BytesPerSecond: 1 << 30}
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".pem"}, Throttle: throttle})
+	start := time.Now()
+	resultCh, err := scanner.Scan(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var scanned int
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		scanned += len(result.CertInfos)
+	}
+	if scanned != 4 {
+		t.Errorf("Expected 4 certificates, got %d", scanned)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("Throttled scan took only %v", elapsed)
+	}
+}
+
+func TestScanThrottleSniff(t *testing.T) {
+	dir := t.TempDir()
+	cert := issueTestCert(t, "sniff.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+	writeTestPEM(t, filepath.Join(dir, "tls.crt"), cert)
+	// Rejected by sniffing, so only its first 4 KB count, not the 100s it
+	// would take to read at 10 KB/s
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), make([]byte, 1<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	throttle := Throttle{BytesPerSecond: 10 << 10}
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Sniff: true, Throttle: throttle})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resultCh, err := scanner.Scan(ctx, []string{dir})
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var scanned int
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		scanned += len(result.CertInfos)
+	}
+	if scanned != 1 {
+		t.Errorf("Expected 1 certificate, got %d", scanned)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("Scan was throttled on the rejected file")
+	}
+}
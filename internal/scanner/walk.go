@@ -0,0 +1,263 @@
+package scanner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"padecer/internal/config"
+)
+
+// SymlinkPolicy decides what the walker does with symbolic links.
+type SymlinkPolicy string
+
+const (
+	SymlinkFollow SymlinkPolicy = "follow" // scan linked files and walk linked directories
+	SymlinkSkip   SymlinkPolicy = "skip"   // ignore symbolic links
+)
+
+// ParseSymlinkPolicy parses "follow" or "skip"; empty means follow.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(s); policy {
+	case "":
+		return SymlinkFollow, nil
+	case SymlinkFollow, SymlinkSkip:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid symlink policy %q: expected follow or skip", s)
+	}
+}
+
+// readDir lists directories; benchmarks replace it to model the latency of
+// network filesystems.
+var readDir = os.ReadDir
+
+// fileKey identifies a file by device and inode, so that hardlinks and the
+// targets of symlinks are recognized.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// walk is the state of walking one of the paths given to Scan. The visited
+// directories and files are shared by all of them, so each inode is read
+// once per scan.
+type walk struct {
+	top      string
+	topKey   fileKey
+	hasTop   bool
+	tops     []string // all paths given to Scan, symlinks resolved
+	fileCh   chan<- string
+	resultCh chan<- ScanResult
+	visited  *visited
+	mounts   map[string]string // mount point to filesystem type
+	deadline time.Time         // end of the scan budget, if any
+}
+
+type visited struct {
+	mu    sync.Mutex
+	dirs  map[fileKey]bool
+	files map[fileKey]bool
+}
+
+func (s *Scanner) newWalk(paths []string, fileCh chan<- string, resultCh chan<- ScanResult) *walk {
+	w := &walk{
+		fileCh:   fileCh,
+		resultCh: resultCh,
+		visited:  &visited{dirs: make(map[fileKey]bool), files: make(map[fileKey]bool)},
+	}
+	if s.limits.ScanBudget > 0 {
+		w.deadline = time.Now().Add(s.limits.ScanBudget)
+	}
+	for _, path := range paths {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			w.tops = append(w.tops, resolved)
+		}
+	}
+	if s.oneFilesystem {
+		mounts, err := loadMounts()
+		if err != nil {
+			config.Log.Warn("Failed to read mount points, comparing devices only", "error", err)
+		}
+		w.mounts = mounts
+	}
+	return w
+}
+
+// forRoot returns the walk of top, sharing the visited sets of w.
+func (w *walk) forRoot(top string) *walk {
+	root := *w
+	root.top = top
+	root.hasTop = false
+	if fi, err := os.Stat(top); err == nil {
+		root.topKey, root.hasTop = fileKeyOf(fi)
+	}
+	return &root
+}
+
+// firstVisit reports whether the directory or file described by fi has not
+// been seen yet in this scan, and marks it seen. Files whose identity is
+// unknown are always visited.
+func (w *walk) firstVisit(fi fs.FileInfo) bool {
+	key, ok := fileKeyOf(fi)
+	if !ok {
+		return true
+	}
+
+	set := w.visited.files
+	if fi.IsDir() {
+		set = w.visited.dirs
+	}
+
+	w.visited.mu.Lock()
+	defer w.visited.mu.Unlock()
+	if set[key] {
+		return false
+	}
+	set[key] = true
+	return true
+}
+
+// pastDeadline reports whether the scan budget is spent.
+func (w *walk) pastDeadline() bool {
+	return !w.deadline.IsZero() && time.Now().After(w.deadline)
+}
+
+// insideTops reports whether the linked directory at dir leads back into
+// one of the paths given to Scan, which are walked through their own
+// paths. Following such links would only report the same files under
+// other names, depending on which walker gets to them first.
+func (w *walk) insideTops(dir string) bool {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	for _, top := range w.tops {
+		if within(top, resolved) {
+			return true
+		}
+	}
+	return false
+}
+
+// otherFilesystem returns the type of the filesystem mounted on dir, or
+// "unknown" when dir is on a different device than the walked path, and
+// the empty string when dir is on the same filesystem.
+func (w *walk) otherFilesystem(dir string, fi fs.FileInfo) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		if fsType, ok := w.mounts[abs]; ok {
+			return fsType
+		}
+	}
+	if key, ok := fileKeyOf(fi); ok && w.hasTop && key.dev != w.topKey.dev {
+		return "unknown"
+	}
+	return ""
+}
+
+// dirTask is a directory waiting to be walked.
+type dirTask struct {
+	w     *walk
+	path  string
+	depth int
+}
+
+// walkPool runs directory walkers that each keep a queue of directories:
+// a walker takes the directory it queued last, staying depth-first and
+// close to what it just read, and steals the oldest directory, usually the
+// largest subtree, from another walker when its own queue is empty.
+type walkPool struct {
+	queues  []walkQueue
+	pending atomic.Int64 // directories queued or being walked
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	seq  uint64 // incremented on every push, to wake idle walkers
+}
+
+type walkQueue struct {
+	mu    sync.Mutex
+	tasks []dirTask
+}
+
+func newWalkPool(walkers int) *walkPool {
+	p := &walkPool{queues: make([]walkQueue, walkers)}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// push queues a directory on the queue of walker i.
+func (p *walkPool) push(i int, task dirTask) {
+	p.pending.Add(1)
+	q := &p.queues[i]
+	q.mu.Lock()
+	q.tasks = append(q.tasks, task)
+	q.mu.Unlock()
+
+	p.mu.Lock()
+	p.seq++
+	p.cond.Signal()
+	p.mu.Unlock()
+}
+
+// take returns the newest directory of walker i, or steals the oldest one
+// of another walker.
+func (p *walkPool) take(i int) (dirTask, bool) {
+	q := &p.queues[i]
+	q.mu.Lock()
+	if n := len(q.tasks); n > 0 {
+		task := q.tasks[n-1]
+		q.tasks = q.tasks[:n-1]
+		q.mu.Unlock()
+		return task, true
+	}
+	q.mu.Unlock()
+
+	for j := 1; j < len(p.queues); j++ {
+		victim := &p.queues[(i+j)%len(p.queues)]
+		victim.mu.Lock()
+		if len(victim.tasks) > 0 {
+			task := victim.tasks[0]
+			victim.tasks = victim.tasks[1:]
+			victim.mu.Unlock()
+			return task, true
+		}
+		victim.mu.Unlock()
+	}
+	return dirTask{}, false
+}
+
+// run is the loop of walker i. It returns once every directory has been
+// walked.
+func (p *walkPool) run(i int, walkDir func(task dirTask)) {
+	for {
+		p.mu.Lock()
+		seq := p.seq
+		p.mu.Unlock()
+
+		if task, ok := p.take(i); ok {
+			walkDir(task)
+			if p.pending.Add(-1) == 0 {
+				p.mu.Lock()
+				p.cond.Broadcast()
+				p.mu.Unlock()
+			}
+			continue
+		}
+
+		// Nothing to take: wait for a push, or for the last walker to
+		// finish
+		p.mu.Lock()
+		for p.seq == seq && p.pending.Load() > 0 {
+			p.cond.Wait()
+		}
+		finished := p.pending.Load() == 0
+		p.mu.Unlock()
+		if finished {
+			return
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// fileKeyOf is unavailable without inodes: hardlinks are read once per path
+// and symlink loops are only stopped by MaxDepth.
+func fileKeyOf(fs.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
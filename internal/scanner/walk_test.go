@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestWalkPool(t *testing.T) {
+	// A tree of 4 levels, 5 subdirectories each, discovered while walking
+	const fanout, levels = 5, 4
+	pool := newWalkPool(4)
+	pool.push(0, dirTask{path: "root"})
+
+	var mu sync.Mutex
+	walked := make(map[string]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(i, func(task dirTask) {
+				mu.Lock()
+				walked[task.path]++
+				mu.Unlock()
+				if task.depth == levels {
+					return
+				}
+				for j := 0; j < fanout; j++ {
+					pool.push(i, dirTask{path: fmt.Sprintf("%s/%d", task.path, j), depth: task.depth + 1})
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	want := 0
+	for level, n := 0, 1; level <= levels; level, n = level+1, n*fanout {
+		want += n
+	}
+	if len(walked) != want {
+		t.Errorf("Walked %d directories, want %d", len(walked), want)
+	}
+	for path, n := range walked {
+		if n != 1 {
+			t.Errorf("%s walked %d times", path, n)
+		}
+	}
+}
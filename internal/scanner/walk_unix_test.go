@@ -0,0 +1,168 @@
+//go:build unix
+
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func scanPaths(t *testing.T, opts Options, paths ...string) []string {
+	t.Helper()
+	opts.Extensions = []string{".pem", ".0"}
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), opts)
+	resultCh, err := scanner.Scan(context.Background(), paths)
+	if err != nil {
+		t.Fatalf("Scan() failed: %v", err)
+	}
+
+	var scanned []string
+	for result := range resultCh {
+		if result.Error != nil {
+			t.Errorf("Unexpected error: %v", result.Error)
+		}
+		if len(result.CertInfos) > 0 {
+			rel, _ := filepath.Rel(paths[0], result.Path)
+			scanned = append(scanned, filepath.ToSlash(rel))
+		}
+	}
+	slices.Sort(scanned)
+	return scanned
+}
+
+func TestWalkLinks(t *testing.T) {
+	dir := t.TempDir()
+	cert := issueTestCert(t, "links.example.com", false, time.Now().AddDate(1, 0, 0), nil)
+
+	// /etc/ssl/certs style hash links, a hardlink, linked directories
+	// inside and outside the scanned path, and a symlink loop.
+	outside := t.TempDir()
+	writeTestPEM(t, filepath.Join(outside, "external.pem"), cert)
+	shared := filepath.Join(dir, "shared")
+	if err := os.Mkdir(shared, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(dir, "bundle.pem"), cert)
+	writeTestPEM(t, filepath.Join(shared, "other.pem"), cert)
+	links := map[string]string{
+		"1a2b3c4d.0": "bundle.pem",
+		"5e6f7a8b.0": "bundle.pem",
+		"linked":     "shared",
+		"shared/up":  "..",
+		"external":   outside,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "bundle.pem"), filepath.Join(shared, "hard.pem")); err != nil {
+		t.Fatalf("Failed to create hardlink: %v", err)
+	}
+
+	// Each inode once, under its own name
+	want := []string{"bundle.pem", "external/external.pem", "shared/other.pem"}
+	if got := scanPaths(t, Options{}, dir); !slices.Equal(got, want) {
+		t.Errorf("follow: scanned %v, want %v", got, want)
+	}
+
+	want = []string{"bundle.pem", "shared/other.pem"}
+	if got := scanPaths(t, Options{Symlinks: SymlinkSkip}, dir); !slices.Equal(got, want) {
+		t.Errorf("skip: scanned %v, want %v", got, want)
+	}
+}
+
+func TestWalkProjectedVolume(t *testing.T) {
+	// A Kubernetes volume: the files live in a versioned directory,
+	// exposed through "..data" and a symlink per file
+	dir := t.TempDir()
+	version := filepath.Join(dir, "..2026_01_01_00_00_00.123456789")
+	if err := os.Mkdir(version, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(version, "ca.pem"), issueTestCert(t, "volume.example.com", false, time.Now().AddDate(1, 0, 0), nil))
+	for link, target := range map[string]string{"..data": filepath.Base(version), "ca.pem": "..data/ca.pem"} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	// Other directories starting with ".." are not part of the volume
+	backup := filepath.Join(dir, "..backup")
+	if err := os.Mkdir(backup, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	writeTestPEM(t, filepath.Join(backup, "old.pem"), issueTestCert(t, "backup.example.com", false, time.Now().AddDate(1, 0, 0), nil))
+
+	want := []string{"..backup/old.pem", "ca.pem"}
+	if got := scanPaths(t, Options{}, dir); !slices.Equal(got, want) {
+		t.Errorf("follow: scanned %v, want %v", got, want)
+	}
+
+	want = []string{filepath.Base(version) + "/ca.pem", "..backup/old.pem"}
+	if got := scanPaths(t, Options{Symlinks: SymlinkSkip}, dir); !slices.Equal(got, want) {
+		t.Errorf("skip: scanned %v, want %v", got, want)
+	}
+}
+
+func TestOtherFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	proc := filepath.Join(dir, "proc")
+	if err := os.Mkdir(proc, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	w := (&walk{mounts: map[string]string{proc: "proc"}}).forRoot(dir)
+	fi, err := os.Stat(proc)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if got := w.otherFilesystem(proc, fi); got != "proc" {
+		t.Errorf("otherFilesystem(%s) = %q, want proc", proc, got)
+	}
+
+	fi, err = os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if got := w.otherFilesystem(dir, fi); got != "" {
+		t.Errorf("otherFilesystem(%s) = %q, want the same filesystem", dir, got)
+	}
+}
+
+func TestProcessFileInterrupted(t *testing.T) {
+	// Reading a FIFO without a writer blocks until the file times out
+	fifo := filepath.Join(t.TempDir(), "blocked.pem")
+	if err := syscall.Mkfifo(fifo, 0600); err != nil {
+		t.Skipf("Cannot create a FIFO: %v", err)
+	}
+	t.Cleanup(func() {
+		// Release the blocked readers
+		for range 2 {
+			if f, err := os.OpenFile(fifo, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+				f.Close()
+			}
+		}
+	})
+
+	scanner := New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{Limits: Limits{FileTimeout: 50 * time.Millisecond}})
+	result := scanner.processFileWithContext(context.Background(), "", fifo)
+	if result.Path != fifo || KindOf(result.Error) != ErrorTimeout {
+		t.Errorf("Expected a %s error for %s, got %q: %v", ErrorTimeout, fifo, result.Path, result.Error)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	scanner = New(NewParser(false, 30), shutdown.NewManager(30*time.Second), Options{})
+	result = scanner.processFileWithContext(ctx, "", fifo)
+	if result.Path != fifo || KindOf(result.Error) != ErrorCancelled {
+		t.Errorf("Expected a %s error for %s, got %q: %v", ErrorCancelled, fifo, result.Path, result.Error)
+	}
+}
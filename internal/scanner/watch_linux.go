@@ -0,0 +1,331 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"padecer/internal/config"
+)
+
+// watchMask selects the events worth another look: files closed after
+// writing or moved into place, new directories and symlinks, and changed
+// permissions, which matter for private keys.
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_ONLYDIR
+
+// watchDelay is how long a file must be left alone before it is parsed, so
+// that a certificate and its key written in turn are read once, complete.
+const watchDelay = time.Second
+
+// watcher keeps inotify watches on the directories of the paths given to
+// Watch.
+type watcher struct {
+	s     *Scanner
+	paths []string
+	walk  *walk // for the mount points, with --one-filesystem
+	fd    int
+	file  *os.File // fd, read through the runtime poller
+
+	mu      sync.Mutex
+	dirs    map[int32]string // watch descriptors
+	full    bool             // the kernel limit of watches was reached
+	changed map[string]time.Time
+}
+
+// Watch scans paths, then keeps watching them with inotify: files that are
+// created, written or moved are parsed again as soon as they settle, and new
+// directories are watched in turn. Everything, endpoints and pairs included,
+// is scanned again every rescan interval, if not zero, and whenever the kernel
+// dropped events. Results are sent until ctx is done.
+func (s *Scanner) Watch(ctx context.Context, paths []string, rescan time.Duration) (<-chan ScanResult, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	wt := &watcher{
+		s:       s,
+		paths:   paths,
+		walk:    s.newWalk(paths, nil, nil),
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		dirs:    make(map[int32]string),
+		changed: make(map[string]time.Time),
+	}
+
+	resultCh := make(chan ScanResult, s.limits.BufferSize)
+	changedCh := make(chan string, s.limits.BufferSize)
+	overflowCh := make(chan struct{}, 1)
+	var wg, filesWg sync.WaitGroup
+
+	// Closing the inotify file ends the pending read
+	go func() {
+		<-ctx.Done()
+		wt.file.Close()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wt.readEvents(overflowCh)
+	}()
+
+	filesWg.Add(1)
+	go func() {
+		defer filesWg.Done()
+		defer close(changedCh)
+		wt.flushChanged(ctx, changedCh)
+	}()
+
+	workCh := (<-chan string)(changedCh)
+	if s.throttle != (Throttle{}) {
+		throttledCh := make(chan string, s.limits.BufferSize)
+		workCh = throttledCh
+		filesWg.Add(1)
+		go func() {
+			defer filesWg.Done()
+			defer close(throttledCh)
+			s.throttleFiles(ctx, changedCh, throttledCh)
+		}()
+	}
+	for i := 0; i < s.limits.Workers; i++ {
+		filesWg.Add(1)
+		go func() {
+			defer filesWg.Done()
+			s.processFiles(ctx, paths, workCh, resultCh)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		wt.rescan(ctx, rescan, overflowCh, resultCh)
+	}()
+
+	go func() {
+		wg.Wait()
+		filesWg.Wait()
+		close(resultCh)
+	}()
+
+	return resultCh, nil
+}
+
+// rescan watches and scans all paths, then does it again on every tick and
+// after an overflow. Watches are added again because the directories created
+// while events were dropped are not watched yet.
+func (wt *watcher) rescan(ctx context.Context, interval time.Duration, overflowCh <-chan struct{}, resultCh chan<- ScanResult) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		for _, path := range wt.paths {
+			wt.addWatches(ctx, wt.walk.forRoot(path), path, 0, nil)
+		}
+		wt.mu.Lock()
+		config.Log.Info("Watching for changes", "directories", len(wt.dirs))
+		wt.mu.Unlock()
+
+		scanCh, err := wt.s.Scan(ctx, wt.paths)
+		if err != nil {
+			config.Log.Error("Failed to start scan", "error", err)
+			return
+		}
+		for result := range scanCh {
+			select {
+			case resultCh <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-overflowCh:
+			config.Log.Warn("Watch events lost, scanning everything again")
+		}
+	}
+}
+
+// addWatches watches dir and the directories below it, the way Scan walks
+// them, and hands the files selected in them to queue, if not nil.
+// Directories reached through symlinks are left to the rescans.
+func (wt *watcher) addWatches(ctx context.Context, w *walk, dir string, depth int, queue func(string)) {
+	s := wt.s
+	if depth > s.limits.MaxDepth || ctx.Err() != nil {
+		return
+	}
+
+	wd, err := syscall.InotifyAddWatch(wt.fd, dir, watchMask)
+	wt.mu.Lock()
+	switch {
+	case err == nil:
+		wt.dirs[int32(wd)] = dir
+	case errors.Is(err, syscall.ENOSPC):
+		if !wt.full {
+			config.Log.Warn("Too many directories to watch, see fs.inotify.max_user_watches", "path", dir)
+			wt.full = true
+		}
+	default:
+		config.Log.Debug("Failed to watch directory", "path", dir, "error", err)
+	}
+	wt.mu.Unlock()
+	if err != nil && queue == nil {
+		return
+	}
+
+	entries, err := readDir(dir)
+	if err != nil && len(entries) == 0 {
+		return
+	}
+	root := s.rootFor(dir)
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			if (s.symlinks != SymlinkSkip && isVolumeVersion(dir, entry.Name())) || s.excluded(root, w.top, fullPath, true) {
+				continue
+			}
+			if s.oneFilesystem {
+				if fi, err := entry.Info(); err != nil || w.otherFilesystem(fullPath, fi) != "" {
+					continue
+				}
+			}
+			wt.addWatches(ctx, w, fullPath, depth+1, queue)
+		case queue != nil:
+			if entry.Type()&os.ModeSymlink != 0 && s.symlinks == SymlinkSkip {
+				continue
+			}
+			if s.selected(root, w.top, fullPath) {
+				queue(fullPath)
+			}
+		}
+	}
+}
+
+// readEvents records the paths that inotify reports as changed, until the
+// inotify file is closed.
+func (wt *watcher) readEvents(overflowCh chan<- struct{}) {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := wt.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[off:]))
+			mask := binary.NativeEndian.Uint32(buf[off+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[off+12:]))
+			off += syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[off:min(off+nameLen, n)]), "\x00")
+			off += nameLen
+
+			if mask&syscall.IN_Q_OVERFLOW != 0 {
+				select {
+				case overflowCh <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			wt.mu.Lock()
+			dir, ok := wt.dirs[wd]
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(wt.dirs, wd)
+			}
+			switch {
+			case !ok || name == "":
+			case strings.HasPrefix(name, "..data") || volumeVersionName(name):
+				// Kubernetes swaps the "..data" symlink of a volume,
+				// through "..data_tmp": every file of the directory
+				// changed at once
+				wt.changed[dir] = time.Now()
+			default:
+				wt.changed[filepath.Join(dir, name)] = time.Now()
+			}
+			wt.mu.Unlock()
+		}
+	}
+}
+
+// flushChanged hands the files that have not changed for watchDelay to
+// changedCh, and watches and queues the new directories, until ctx is done.
+func (wt *watcher) flushChanged(ctx context.Context, changedCh chan<- string) {
+	ticker := time.NewTicker(watchDelay / 4)
+	defer ticker.Stop()
+
+	queue := func(fp string) {
+		select {
+		case changedCh <- fp:
+		case <-ctx.Done():
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			var settled []string
+			wt.mu.Lock()
+			for fp, at := range wt.changed {
+				if now.Sub(at) >= watchDelay {
+					settled = append(settled, fp)
+					delete(wt.changed, fp)
+				}
+			}
+			wt.mu.Unlock()
+
+			for _, fp := range settled {
+				wt.flush(ctx, fp, queue)
+			}
+		}
+	}
+}
+
+// flush looks at a changed path again: files selected by the rules of their
+// path are queued, directories are watched and their files queued.
+func (wt *watcher) flush(ctx context.Context, fp string, queue func(string)) {
+	s := wt.s
+	top := topFor(wt.paths, fp)
+	if top == "" {
+		return
+	}
+
+	fi, err := os.Lstat(fp)
+	if err != nil {
+		return // removed since
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if s.symlinks == SymlinkSkip {
+			return
+		}
+		if target, err := os.Stat(fp); err == nil {
+			fi = target
+		}
+	}
+
+	w := wt.walk.forRoot(top)
+	if fi.IsDir() {
+		if !s.excluded(s.rootFor(fp), top, fp, true) {
+			depth := strings.Count(strings.TrimPrefix(fp, top), string(filepath.Separator))
+			wt.addWatches(ctx, w, fp, depth, queue)
+		}
+		return
+	}
+	if s.selected(s.rootFor(fp), top, fp) {
+		queue(fp)
+	}
+}
@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"padecer/internal/shutdown"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeCert := func(fp, cn string) {
+		t.Helper()
+		writeTestPEM(t, fp, issueTestCert(t, cn, false, time.Now().AddDate(1, 0, 0), nil))
+	}
+	writeCert(filepath.Join(dir, "first.pem"), "first.example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanner := New(NewParser(true, 30), shutdown.NewManager(30*time.Second), Options{Extensions: []string{".pem"}})
+	resultCh, err := scanner.Watch(ctx, []string{dir}, 0)
+	if err != nil {
+		t.Fatalf("Watch() failed: %v", err)
+	}
+
+	waitFor := func(fp, subject string) {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case result := <-resultCh:
+				if result.Error != nil {
+					t.Errorf("Unexpected error: %v", result.Error)
+				}
+				if result.Path == fp && len(result.CertInfos) == 1 && result.CertInfos[0].Subject == subject {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("No result for %s with %s", fp, subject)
+			}
+		}
+	}
+
+	// The initial scan
+	waitFor(filepath.Join(dir, "first.pem"), "CN=first.example.com")
+
+	// A file in a new directory, and a renewed file
+	sub := filepath.Join(dir, "new", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+	writeCert(filepath.Join(sub, "second.pem"), "second.example.com")
+	waitFor(filepath.Join(sub, "second.pem"), "CN=second.example.com")
+
+	writeCert(filepath.Join(dir, "first.pem"), "renewed.example.com")
+	waitFor(filepath.Join(dir, "first.pem"), "CN=renewed.example.com")
+
+	// Files in the new directory are watched too
+	writeCert(filepath.Join(sub, "third.pem"), "third.example.com")
+	waitFor(filepath.Join(sub, "third.pem"), "CN=third.example.com")
+
+	// A Kubernetes volume update: new files in a new directory, exposed by
+	// renaming a symlink over "..data"
+	symlink := func(target, link string) {
+		t.Helper()
+		if err := os.Symlink(target, filepath.Join(sub, link)); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+	for i, version := range []string{"..2026_01_01", "..2026_02_01"} {
+		if err := os.Mkdir(filepath.Join(sub, version), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		writeCert(filepath.Join(sub, version, "tls.pem"), version[2:]+".example.com")
+		symlink(version, "..data_tmp")
+		if err := os.Rename(filepath.Join(sub, "..data_tmp"), filepath.Join(sub, "..data")); err != nil {
+			t.Fatalf("Failed to rename symlink: %v", err)
+		}
+		if i == 0 {
+			symlink("..data/tls.pem", "tls.pem")
+		}
+		waitFor(filepath.Join(sub, "tls.pem"), "CN="+version[2:]+".example.com")
+	}
+
+	cancel()
+	for range resultCh {
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Watch needs inotify, which only Linux has.
+func (s *Scanner) Watch(ctx context.Context, paths []string, rescan time.Duration) (<-chan ScanResult, error) {
+	return nil, fmt.Errorf("watch mode requires inotify: %w", errors.ErrUnsupported)
+}
@@ -0,0 +1,109 @@
+// Package schedule runs a job at the times of an interval or a cron
+// expression, for the daemon mode.
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"padecer/internal/config"
+)
+
+// Schedule returns the time of the run following t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Every runs at the multiples of an interval, counted from the zero time of
+// UTC, so that the hosts of a fleet share the same times before jitter.
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	return t.Truncate(d).Add(d)
+}
+
+func (e Every) String() string {
+	return "@every " + time.Duration(e).String()
+}
+
+// Parse parses an interval, like "6h" or "@every 6h", or a cron expression.
+func Parse(spec string) (Schedule, error) {
+	interval, isEvery := strings.CutPrefix(spec, "@every ")
+	if d, err := time.ParseDuration(strings.TrimSpace(interval)); err == nil {
+		if d < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q: interval shorter than a minute", spec)
+		}
+		return Every(d), nil
+	} else if isEvery {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	return ParseCron(spec)
+}
+
+// Run calls job now, then at every time of s until stop is closed or ctx is
+// done, each time after a random delay of up to jitter, to spread the load
+// of a fleet. Runs never overlap: the times that pass while job runs are
+// skipped, and the next run is the first time of s after it returns.
+func Run(ctx context.Context, stop <-chan struct{}, s Schedule, jitter time.Duration, job func(context.Context)) {
+	next := time.Now()
+	for {
+		if jitter > 0 {
+			next = next.Add(rand.N(jitter))
+		}
+		if !sleepUntil(ctx, stop, next) {
+			return
+		}
+
+		start := time.Now()
+		job(ctx)
+		now := time.Now()
+		if stopped(ctx, stop) {
+			return
+		}
+
+		next = s.Next(now)
+		if next.IsZero() {
+			config.Log.Warn("No further run in schedule", "schedule", s.String())
+			return
+		}
+		if missed := s.Next(start); missed.Before(now) {
+			config.Log.Warn("Run overlapped the next one, which was skipped", "schedule", s.String(), "skipped", missed, "duration", now.Sub(start))
+		}
+		config.Log.Info("Next run scheduled", "at", next, "jitter", jitter)
+	}
+}
+
+// sleepUntil waits until t, and reports false if stop is closed or ctx is
+// done first.
+func sleepUntil(ctx context.Context, stop <-chan struct{}, t time.Time) bool {
+	if stopped(ctx, stop) {
+		return false
+	}
+
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func stopped(ctx context.Context, stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"6h", "@every 6h0m0s"},
+		{"@every 30m", "@every 30m0s"},
+		{"0 3 * * *", "0 3 * * *"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if s.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.spec, s, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"10s", "@every often", "daily"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		stop := make(chan struct{})
+		var runs []time.Duration
+		Run(context.Background(), stop, Every(time.Hour), 0, func(ctx context.Context) {
+			runs = append(runs, time.Since(start))
+			switch len(runs) {
+			case 2:
+				// Overlaps the run at 2h, which is skipped
+				time.Sleep(90 * time.Minute)
+			case 3:
+				close(stop)
+			}
+		})
+
+		want := []time.Duration{0, time.Hour, 3 * time.Hour}
+		if len(runs) != len(want) {
+			t.Fatalf("Ran at %v, want %v", runs, want)
+		}
+		for i := range want {
+			if runs[i] != want[i] {
+				t.Errorf("Ran at %v, want %v", runs, want)
+				break
+			}
+		}
+	})
+}
+
+func TestRunJitter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		start := time.Now()
+		ctx, cancel := context.WithCancel(context.Background())
+		var runs []time.Duration
+		Run(ctx, nil, Every(time.Hour), 10*time.Minute, func(ctx context.Context) {
+			runs = append(runs, time.Since(start))
+			if len(runs) == 5 {
+				cancel()
+			}
+		})
+
+		for i, run := range runs {
+			slot := time.Duration(i) * time.Hour
+			if run < slot || run >= slot+10*time.Minute {
+				t.Errorf("Run %d at %v, want within 10m of %v", i, run, slot)
+			}
+		}
+	})
+}
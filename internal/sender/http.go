@@ -18,15 +18,20 @@ const (
 )
 
 type AlertPayload struct {
-	Host            string    `json:"host"`
-	Timestamp       time.Time `json:"timestamp"`
-	Level           string    `json:"level"`
-	Message         string    `json:"message"`
-	Path            string    `json:"path"`
-	ExpirationDate  time.Time `json:"expirationDate"`
-	DaysUntilExpiry int       `json:"daysUntilExpiry"`
-	Subject         string    `json:"subject,omitempty"`
-	SerialNumber    string    `json:"serialNumber,omitempty"`
+	Host            string             `json:"host"`
+	Timestamp       time.Time          `json:"timestamp"`
+	Level           string             `json:"level"`
+	Message         string             `json:"message"`
+	Path            string             `json:"path"`
+	Alias           string             `json:"alias,omitempty"`
+	ExpirationDate  time.Time          `json:"expirationDate,omitzero"`
+	DaysUntilExpiry int                `json:"daysUntilExpiry"`
+	Subject         string             `json:"subject,omitempty"`
+	SerialNumber    string             `json:"serialNumber,omitempty"`
+	State           string             `json:"state,omitempty"`
+	Type            string             `json:"type,omitempty"`
+	Replaces        string             `json:"replaces,omitempty"` // serial number of the renewed certificate
+	Chain           *scanner.ChainInfo `json:"chain,omitempty"`
 }
 
 type HTTPSender struct {
@@ -54,18 +59,94 @@ func (s *HTTPSender) SendAlert(ctx context.Context, certInfo *scanner.Certificat
 	p := AlertPayload{
 		Host:            config.Hostname,
 		Timestamp:       time.Now(),
-		Level:           "WARN",
-		Message:         "Certificate expiring soon",
+		Level:           string(certInfo.Severity()),
+		Message:         alertMessage(certInfo),
 		Path:            certInfo.Path,
+		Alias:           certInfo.Alias,
 		ExpirationDate:  certInfo.ExpirationDate,
 		DaysUntilExpiry: certInfo.DaysUntilExpiry,
 		Subject:         certInfo.Subject,
 		SerialNumber:    certInfo.SerialNumber,
+		State:           string(certInfo.State),
+		Chain:           certInfo.Chain,
 	}
 
 	return s.send(timeoutCtx, p)
 }
 
+// SendFinding reports a finding, such as a stale served certificate, with
+// its type so the receiver can tell it apart from expiry alerts.
+func (s *HTTPSender) SendFinding(ctx context.Context, f *scanner.Finding) error {
+	if s.endpoint == "" {
+		return nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, AlertTimeout)
+	defer cancel()
+
+	p := AlertPayload{
+		Host:            config.Hostname,
+		Timestamp:       time.Now(),
+		Level:           string(f.Severity),
+		Message:         f.Message,
+		Path:            f.Path,
+		Alias:           f.Alias,
+		ExpirationDate:  f.ExpirationDate,
+		DaysUntilExpiry: f.DaysUntilExpiry,
+		SerialNumber:    f.SerialNumber,
+		Type:            f.Type,
+	}
+
+	return s.send(timeoutCtx, p)
+}
+
+// SendRenewal reports that a certificate alerted on earlier was replaced on
+// disk by one that is no longer due, with the serial number of the former
+// so the receiver can resolve its alert.
+func (s *HTTPSender) SendRenewal(ctx context.Context, renewal scanner.Renewal) error {
+	if s.endpoint == "" {
+		return nil
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, AlertTimeout)
+	defer cancel()
+
+	certInfo := renewal.New
+	p := AlertPayload{
+		Host:            config.Hostname,
+		Timestamp:       time.Now(),
+		Level:           string(scanner.SeverityInfo),
+		Message:         "Certificate renewed",
+		Path:            certInfo.Path,
+		Alias:           certInfo.Alias,
+		ExpirationDate:  certInfo.ExpirationDate,
+		DaysUntilExpiry: certInfo.DaysUntilExpiry,
+		Subject:         certInfo.Subject,
+		SerialNumber:    certInfo.SerialNumber,
+		State:           string(certInfo.State),
+		Type:            "renewed",
+		Replaces:        renewal.Old.SerialNumber,
+	}
+
+	return s.send(timeoutCtx, p)
+}
+
+func alertMessage(certInfo *scanner.CertificateInfo) string {
+	object := "Certificate"
+	if certInfo.Kind == scanner.KindCRL {
+		object = "CRL"
+	}
+
+	switch certInfo.State {
+	case scanner.StateExpired:
+		return object + " expired"
+	case scanner.StateNotYetValid:
+		return object + " not yet valid"
+	default:
+		return object + " expiring soon"
+	}
+}
+
 func (s *HTTPSender) send(ctx context.Context, p AlertPayload) error {
 	data, err := json.Marshal(p)
 	if err != nil {
@@ -10,18 +10,42 @@ type Manager struct {
 	t       time.Duration
 	wg      sync.WaitGroup
 	running atomic.Bool
+	mu      sync.Mutex // orders Start with Shutdown
+	stop    chan struct{}
+	once    sync.Once
 }
 
 func NewManager(t time.Duration) *Manager {
-	mgr := &Manager{t: t}
+	mgr := &Manager{t: t, stop: make(chan struct{})}
 	mgr.running.Store(true)
 	return mgr
 }
 
-func (m *Manager) Shutdown() {m.running.Store(false)}
+func (m *Manager) Shutdown() {
+	m.mu.Lock()
+	m.running.Store(false)
+	m.mu.Unlock()
+	m.once.Do(func() {close(m.stop)})
+}
 func (m *Manager) IsShuttingDown() bool {return !m.running.Load()}
 func (m *Manager) Add(delta int) {m.wg.Add(delta)}
 func (m *Manager) Done() {m.wg.Done()}
+func (m *Manager) Timeout() time.Duration {return m.t}
+
+// Start adds a task, like Add(1), unless Shutdown was called, and reports
+// whether it did. Unlike checking IsShuttingDown before Add, a task started
+// while Shutdown is called is always waited for.
+func (m *Manager) Start() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.running.Load() {return false}
+	m.wg.Add(1)
+	return true
+}
+
+// Stopping is closed by Shutdown, for the goroutines that wait for something
+// else in the meantime, such as the next scheduled scan.
+func (m *Manager) Stopping() <-chan struct{} {return m.stop}
 
 func (m *Manager) Wait() {
 	done := make(chan struct{})
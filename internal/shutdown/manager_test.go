@@ -233,3 +233,53 @@ func TestManager_IsShuttingDown_ThreadSafe(t *testing.T) {
 		_ = shutdownTriggered
 	})
 }
+
+func TestManager_Stopping(t *testing.T) {
+	mgr := NewManager(5 * time.Second)
+
+	select {
+	case <-mgr.Stopping():
+		t.Fatalf("Stopping() closed before Shutdown() call")
+	default:
+	}
+
+	mgr.Shutdown()
+	mgr.Shutdown()
+
+	select {
+	case <-mgr.Stopping():
+	default:
+		t.Errorf("Stopping() should be closed after Shutdown() call")
+	}
+}
+
+func TestManager_Start(t *testing.T) {
+	mgr := NewManager(5 * time.Second)
+
+	if !mgr.Start() {
+		t.Fatalf("Start() failed before Shutdown() call")
+	}
+	mgr.Shutdown()
+	if mgr.Start() {
+		t.Errorf("Start() succeeded after Shutdown() call")
+	}
+
+	// The task started before Shutdown is waited for
+	done := make(chan struct{})
+	go func() {
+		mgr.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("Wait() returned before the started task was done")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mgr.Done()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Wait() did not return after Done() call")
+	}
+}
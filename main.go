@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,6 +15,7 @@ import (
 
 	"padecer/internal/config"
 	"padecer/internal/scanner"
+	"padecer/internal/schedule"
 	"padecer/internal/sender"
 	"padecer/internal/shutdown"
 )
@@ -24,21 +27,44 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	cfg := config.New()
+	if err := cfg.ParseFlags(); err != nil {
+		config.Log.Error("Failed to parse configuration", "error", err)
+		os.Exit(1)
+	}
+
 	shutdownMgr := shutdown.NewManager(30 * time.Second)
+	if cfg.ShutdownTimeout > 0 {
+		shutdownMgr = shutdown.NewManager(cfg.ShutdownTimeout)
+	}
 	go func() {
 		sig := <-sigCh
 		config.Log.Info("Received shutdown signal", "signal", sig.String())
 		shutdownMgr.Shutdown()
+		if cfg.Daemon {
+			// Let the running scan finish, until a second signal or the
+			// shutdown timeout, past which it is cancelled: its results
+			// so far are reported, but the cache is not saved
+			done := make(chan struct{})
+			go func() {
+				shutdownMgr.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case sig := <-sigCh:
+				config.Log.Info("Received second shutdown signal, stopping now", "signal", sig.String())
+			}
+		}
 		cancel()
 	}()
 
-	cfg := config.New()
-	if err := cfg.ParseFlags(); err != nil {
-		config.Log.Error("Failed to parse configuration", "error", err)
-		os.Exit(1)
-	}
-
-	if cfg.Server {
+	if cfg.Daemon {
+		if err := runDaemon(ctx, cfg, shutdownMgr); err != nil {
+			config.Log.Error("Daemon failed", "error", err)
+			os.Exit(1)
+		}
+	} else if cfg.Server {
 		if err := runServer(ctx, cfg, shutdownMgr); err != nil {
 			config.Log.Error("Server failed", "error", err)
 			os.Exit(1)
@@ -52,85 +78,357 @@ func main() {
 	config.Log.Info("Padecer shutdown completed")
 }
 
+// runDaemon scans on the schedule of the configuration until shutdown. Each
+// scan runs with a shutdown manager of its own, so that a signal lets it
+// finish, while shutdownMgr stops the schedule and waits for it.
+func runDaemon(ctx context.Context, cfg *config.Config, shutdownMgr *shutdown.Manager) error {
+	sched, err := schedule.Parse(cfg.Schedule)
+	if err != nil {
+		return err
+	}
+	config.Log.Info("Daemon started", "schedule", sched.String(), "jitter", cfg.Jitter)
+
+	schedule.Run(ctx, shutdownMgr.Stopping(), sched, cfg.Jitter, func(ctx context.Context) {
+		if !shutdownMgr.Start() {
+			return
+		}
+		defer shutdownMgr.Done()
+		if err := execute(ctx, config.Hostname, shutdown.NewManager(shutdownMgr.Timeout()), cfg); err != nil {
+			config.Log.Error("Scan failed", "error", err)
+		}
+	})
+	return nil
+}
+
 func execute(ctx context.Context, h string, shutdownMgr *shutdown.Manager, cfg *config.Config) error {
+	if cfg.LowPriority {
+		if err := scanner.LowerPriority(); err != nil {
+			config.Log.Warn("Failed to lower priority", "error", err)
+		}
+	}
 
-	if cfg.ShutdownTimeout > 0 {
-		shutdownMgr = shutdown.NewManager(cfg.ShutdownTimeout)
+	p := scanner.NewParser(cfg.IncludeSubject || cfg.Inventory, cfg.Days)
+
+	passwords, err := cfg.KeystorePasswords()
+	if err != nil {
+		return err
+	}
+	p.SetPasswords(passwords)
+
+	if len(cfg.Lint) > 0 {
+		rules, err := scanner.ParseLintRules(cfg.Lint)
+		if err != nil {
+			return err
+		}
+		p.SetLintRules(rules)
+	}
+
+	if cfg.VerifyChain || cfg.CABundle != "" {
+		var roots *x509.CertPool
+		if cfg.CABundle != "" {
+			if roots, err = scanner.LoadRoots(cfg.CABundle); err != nil {
+				return err
+			}
+		}
+		p.SetVerification(roots)
+	}
+
+	// Chain verification needs the certificates themselves: the cache would
+	// only be emptied by Save
+	var cache *scanner.Cache
+	if cfg.Cache != "" && (cfg.VerifyChain || cfg.CABundle != "") {
+		config.Log.Warn("Cache not used with chain verification", "path", cfg.Cache)
+	} else if cfg.Cache != "" {
+		if cache, err = scanner.OpenCache(cfg.Cache); err != nil {
+			return err
+		}
+		p.SetCache(cache)
 	}
-	p := scanner.NewParser(cfg.IncludeSubject, cfg.Days)
 
 	httpSender := sender.NewHTTPSender(cfg.SendTo)
 	defer httpSender.Close()
 
-	s := scanner.New(p, shutdownMgr, cfg.Extensions)
-	config.Log.Info("Certificate scan configuration", "days_threshold", cfg.Days, "paths", cfg.Paths, "ext", cfg.Extensions)
+	endpoints := make([]scanner.Endpoint, 0, len(cfg.Endpoints))
+	for _, e := range cfg.Endpoints {
+		endpoint, err := scanner.ParseEndpoint(e)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	pairs := make([]scanner.Pair, 0, len(cfg.Pairs))
+	for _, e := range cfg.Pairs {
+		pair, err := scanner.ParsePair(e)
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, pair)
+	}
+
+	include, err := scanner.ParsePatterns(cfg.Include)
+	if err != nil {
+		return err
+	}
+	exclude, err := scanner.ParsePatterns(cfg.Exclude)
+	if err != nil {
+		return err
+	}
 
-	resultCh, err := s.Scan(ctx, cfg.Paths)
+	symlinks, err := scanner.ParseSymlinkPolicy(cfg.Symlinks)
+	if err != nil {
+		return err
+	}
+
+	roots := make([]scanner.Root, 0, len(cfg.Roots))
+	for path, r := range cfg.Roots {
+		root := scanner.Root{Path: path, Days: r.Days, Extensions: r.Extensions}
+		if root.Include, err = scanner.ParsePatterns(r.Include); err != nil {
+			return err
+		}
+		if root.Exclude, err = scanner.ParsePatterns(r.Exclude); err != nil {
+			return err
+		}
+		roots = append(roots, root)
+	}
+
+	maxDepth := cfg.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = scanner.TopLevelOnly
+	}
+	s := scanner.New(p, shutdownMgr, scanner.Options{
+		Extensions:    cfg.Extensions,
+		Endpoints:     endpoints,
+		Pairs:         pairs,
+		KeyOwners:     cfg.KeyOwners,
+		Sniff:         cfg.Sniff,
+		Include:       include,
+		Exclude:       exclude,
+		Roots:         roots,
+		Symlinks:      symlinks,
+		OneFilesystem: cfg.OneFilesystem,
+		Walkers:       cfg.Walkers,
+		Limits: scanner.Limits{
+			Workers:     cfg.Workers,
+			MaxDepth:    maxDepth,
+			BufferSize:  cfg.BufferSize,
+			MaxFileSize: cfg.MaxFileSize,
+			FileTimeout: cfg.FileTimeout,
+			ScanBudget:  cfg.ScanBudget,
+		},
+		Throttle: scanner.Throttle{
+			FilesPerSecond: cfg.FilesPerSecond,
+			BytesPerSecond: cfg.BytesPerSecond,
+		},
+	})
+	config.Log.Info("Certificate scan configuration", "days_threshold", cfg.Days, "paths", cfg.Paths, "ext", cfg.Extensions, "endpoints", cfg.Endpoints, "pairs", cfg.Pairs, "inventory", cfg.Inventory, "sniff", cfg.Sniff, "include", cfg.Include, "exclude", cfg.Exclude, "scan_budget", cfg.ScanBudget, "files_per_second", cfg.FilesPerSecond, "bytes_per_second", cfg.BytesPerSecond, "watch", cfg.Watch)
+
+	var resultCh <-chan scanner.ScanResult
+	if cfg.Watch {
+		resultCh, err = s.Watch(ctx, cfg.Paths, cfg.RescanInterval)
+	} else {
+		resultCh, err = s.Scan(ctx, cfg.Paths)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to start scan: %w", err)
 	}
 
-	var processedCount, warningCount, errorCount int
+	var processedCount, crlCount, csrCount, warningCount, errorCount, lockedCount, findingCount, chainCount, keyCount, renewedCount int
+	// In watch mode, the certificates alerted on, to tell when they are
+	// replaced
+	var renewals *scanner.Renewals
+	if cfg.Watch {
+		renewals = scanner.NewRenewals()
+	}
+	errorKinds := make(map[scanner.ErrorKind]int)
+	countError := func(err error) {
+		errorCount++
+		kind := scanner.KindOf(err)
+		if kind == "" {
+			kind = "other"
+		}
+		errorKinds[kind]++
+		config.Log.Error("Scan error", "error", err, "kind", kind)
+	}
+	reportFinding := func(finding *scanner.Finding) {
+		findingCount++
+		fmt.Fprintf(os.Stderr, "%s::%s => %s\n", h, location(finding.Path, finding.Alias), finding.Message)
+
+		record := struct {
+			Host string `json:"host"`
+			*scanner.Finding
+		}{h, finding}
+		if data, err := json.Marshal(record); err == nil {
+			fmt.Println(string(data))
+		}
+
+		if err := httpSender.SendFinding(ctx, finding); err != nil {
+			config.Log.Error("Failed to send HTTP alert", "path", finding.Path, "error", err)
+		}
+	}
+
 	for result := range resultCh {
 		if shutdownMgr.IsShuttingDown() {
 			config.Log.Info("Shutdown requested, stopping processing")
 			break
 		}
 
+		// Locked keystores are expected, so counted apart from errors,
+		// but still under their kind
+		if errors.Is(result.Error, scanner.ErrNoMatchingPassword) {
+			lockedCount++
+			errorKinds[scanner.KindOf(result.Error)]++
+			config.Log.Warn("Encrypted keystore skipped", "error", result.Error)
+			continue
+		}
+
 		if result.Error != nil {
-			errorCount++
-			config.Log.Error("Scan error", "error", result.Error)
+			countError(result.Error)
 			continue
 		}
 
+		// Bad blocks do not hide the certificates parsed around them
+		for _, err := range result.Errors {
+			countError(err)
+		}
+
+		keyCount += len(result.Keys)
+		if cfg.Inventory && result.Path != "" {
+			record := struct {
+				Host    string   `json:"host"`
+				Path    string   `json:"path"`
+				Objects []string `json:"objects"`
+			}{h, result.Path, result.Objects}
+
+			if data, err := json.Marshal(record); err == nil {
+				fmt.Println(string(data))
+			}
+		}
+
+		for _, finding := range result.Findings {
+			reportFinding(finding)
+		}
+
 		for _, certInfo := range result.CertInfos {
-			processedCount++
-			if certInfo.IsExpiringSoon {
+			switch certInfo.Kind {
+			case scanner.KindCRL:
+				crlCount++
+			case scanner.KindCSR:
+				csrCount++
+			default:
+				processedCount++
+			}
+			if certInfo.Chain != nil {
+				if problem := certInfo.Chain.Problem(); problem != "" {
+					chainCount++
+					config.Log.Warn("Certificate chain problem", "path", location(certInfo.Path, certInfo.Alias), "problem", problem)
+				}
+			}
+
+			for _, finding := range certInfo.Findings {
+				reportFinding(finding)
+			}
+
+			if certInfo.NeedsAlert() {
 				warningCount++
-				fmt.Fprintf(os.Stderr, "%s::%s => %s\n", h, certInfo.Path, certInfo.ExpirationDate.Format("2006-01-02T15:04:05Z07:00"))
+				fmt.Fprintf(os.Stderr, "%s::%s => %s\n", h, location(certInfo.Path, certInfo.Alias), certInfo.ExpirationDate.Format("2006-01-02T15:04:05Z07:00"))
 
 				if err := httpSender.SendAlert(ctx, certInfo); err != nil {
 					config.Log.Error("Failed to send HTTP alert", "path", certInfo.Path, "error", err)
 				}
-			} else {
+			} else if !cfg.Inventory {
 				outputCert := struct {
-					Host            string `json:"host"`
-					Path            string `json:"path"`
-					Expires         string `json:"expires"`
-					DaysUntilExpiry int    `json:"daysUntilExpiry"`
-					Subject         string `json:"subject,omitempty"`
-					SerialNumber    string `json:"serialNumber,omitempty"`
+					Host            string             `json:"host"`
+					Path            string             `json:"path"`
+					Alias           string             `json:"alias,omitempty"`
+					Kind            string             `json:"kind,omitempty"`
+					Expires         string             `json:"expires,omitempty"`
+					DaysUntilExpiry int                `json:"daysUntilExpiry"`
+					Subject         string             `json:"subject,omitempty"`
+					SerialNumber    string             `json:"serialNumber,omitempty"`
+					Chain           *scanner.ChainInfo `json:"chain,omitempty"`
 				}{
 					Host:            h,
 					Path:            certInfo.Path,
-					Expires:         certInfo.ExpirationDate.Format("2006-01-02T15:04:05Z07:00"),
+					Alias:           certInfo.Alias,
+					Kind:            certInfo.Kind,
 					DaysUntilExpiry: certInfo.DaysUntilExpiry,
 					Subject:         certInfo.Subject,
 					SerialNumber:    certInfo.SerialNumber,
+					Chain:           certInfo.Chain,
+				}
+				if !certInfo.ExpirationDate.IsZero() {
+					outputCert.Expires = certInfo.ExpirationDate.Format("2006-01-02T15:04:05Z07:00")
 				}
 
 				if data, err := json.Marshal(outputCert); err == nil {
 					fmt.Println(string(data))
 				}
 			}
+
+			if cfg.Inventory {
+				record := struct {
+					Host string `json:"host"`
+					*scanner.CertificateInfo
+				}{h, certInfo}
+
+				if data, err := json.Marshal(record); err == nil {
+					fmt.Println(string(data))
+				}
+			}
+		}
+
+		if renewals != nil {
+			for _, renewal := range renewals.Update(result.CertInfos) {
+				renewedCount++
+				certInfo := renewal.New
+				config.Log.Info("Certificate renewed", "path", location(certInfo.Path, certInfo.Alias), "expires", certInfo.ExpirationDate, "replaces", renewal.Old.SerialNumber)
+				if err := httpSender.SendRenewal(ctx, renewal); err != nil {
+					config.Log.Error("Failed to send HTTP alert", "path", certInfo.Path, "error", err)
+				}
+			}
+		}
+	}
+
+	// An interrupted scan has not seen every file, which Save would forget
+	if cache != nil && !shutdownMgr.IsShuttingDown() && ctx.Err() == nil {
+		hits, misses := cache.Stats()
+		if err := cache.Save(); err != nil {
+			config.Log.Error("Failed to save cache", "path", cfg.Cache, "error", err)
+		} else {
+			config.Log.Info("Cache saved", "path", cfg.Cache, "hits", hits, "misses", misses)
 		}
 	}
 
-	config.Log.Info("Scan completed", "processed", processedCount, "warnings", warningCount, "errors", errorCount)
+	config.Log.Info("Scan completed", "processed", processedCount, "crls", crlCount, "csrs", csrCount, "keys", keyCount, "warnings", warningCount, "errors", errorCount, "locked", lockedCount, "findings", findingCount, "chain_problems", chainCount, "renewed", renewedCount, "errors_by_kind", errorKinds)
 	shutdownMgr.Wait()
 	return nil
 }
 
+// location identifies a certificate for humans: its path, plus the keystore
+// entry it was found under, if any.
+func location(path, alias string) string {
+	if alias == "" {
+		return path
+	}
+	return fmt.Sprintf("%s[%s]", path, alias)
+}
+
 type Alert struct {
-	Host            string    `json:"host"`
-	Timestamp       time.Time `json:"timestamp"`
-	Level           string    `json:"level"`
-	Message         string    `json:"message"`
-	Path            string    `json:"path"`
-	ExpirationDate  time.Time `json:"expirationDate"`
-	DaysUntilExpiry int       `json:"daysUntilExpiry"`
-	Subject         string    `json:"subject,omitempty"`
-	SerialNumber    string    `json:"serialNumber,omitempty"`
+	Host            string             `json:"host"`
+	Timestamp       time.Time          `json:"timestamp"`
+	Level           string             `json:"level"`
+	Message         string             `json:"message"`
+	Path            string             `json:"path"`
+	Alias           string             `json:"alias,omitempty"`
+	ExpirationDate  time.Time          `json:"expirationDate,omitzero"`
+	DaysUntilExpiry int                `json:"daysUntilExpiry"`
+	Subject         string             `json:"subject,omitempty"`
+	SerialNumber    string             `json:"serialNumber,omitempty"`
+	State           string             `json:"state,omitempty"`
+	Type            string             `json:"type,omitempty"`
+	Replaces        string             `json:"replaces,omitempty"`
+	Chain           *scanner.ChainInfo `json:"chain,omitempty"`
 }
 
 func runServer(ctx context.Context, cfg *config.Config, shutdownMgr *shutdown.Manager) error {
@@ -183,7 +481,8 @@ func handleAlert(w http.ResponseWriter, r *http.Request, f string) {
 		return
 	}
 
-	if alert.Host == "" || alert.Path == "" || alert.ExpirationDate.IsZero() {
+	// Findings such as orphan keys are not about an expiry date.
+	if alert.Host == "" || alert.Path == "" || (alert.Type == "" && alert.ExpirationDate.IsZero()) {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
@@ -199,13 +498,23 @@ func handleAlert(w http.ResponseWriter, r *http.Request, f string) {
 		return
 	}
 
+	// The certificates of a bundle share a path, and lint findings a type
 	existingIndex := -1
 	for i, a := range alerts {
-		if a.Host == alert.Host && a.Path == alert.Path {
+		if a.Host == alert.Host && a.Path == alert.Path && a.Alias == alert.Alias && a.Type == alert.Type && a.SerialNumber == alert.SerialNumber {
 			existingIndex = i
 			break
 		}
 	}
+	// A renewal takes the place of the expiry alert it resolves
+	if alert.Type == "renewed" {
+		for i, a := range alerts {
+			if a.Host == alert.Host && a.Path == alert.Path && a.Alias == alert.Alias && a.Type == "" && (alert.Replaces == "" || a.SerialNumber == alert.Replaces) {
+				existingIndex = i
+				break
+			}
+		}
+	}
 
 	if existingIndex >= 0 {
 		alerts[existingIndex] = alert